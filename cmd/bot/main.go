@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/qmitry/opinion-alert-bot/internal/api"
 	"github.com/qmitry/opinion-alert-bot/internal/config"
@@ -56,14 +57,14 @@ func main() {
 
 	// Initialize Telegram bot
 	log.Info("Initializing Telegram bot...")
-	bot, err := telegram.NewBot(cfg.Telegram.Token, db, apiClient, log)
+	bot, err := telegram.NewBot(cfg.Telegram.Token, db, apiClient, time.Duration(cfg.App.AlertCooldown)*time.Second, log)
 	if err != nil {
 		log.Fatalf("Failed to initialize Telegram bot: %v", err)
 	}
 
 	// Initialize monitor
 	log.Info("Initializing market monitor...")
-	mon := monitor.NewMonitor(db, apiClient, bot, cfg.App.PollInterval, log)
+	mon := monitor.NewMonitor(db, apiClient, bot, cfg.App, log)
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -6,10 +6,44 @@ import (
 )
 
 // GetMarketDetails fetches detailed information about a specific market (binary or multi-outcome)
-// For multi-outcome markets, it automatically uses the first child market's token
+// For multi-outcome markets, each outcome's YES token is available in ChildMarkets
 func (c *Client) GetMarketDetails(ctx context.Context, marketID string) (*MarketDetail, error) {
 	// Try binary market endpoint first
+	result, err := c.fetchMarketDetails(ctx, marketID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// If errno=10200, it might be a categorical market - try that endpoint
+	if result.Errno == 10200 {
+		c.log.Debugf("Market %s not found as binary, trying categorical endpoint...", marketID)
+		result, err = c.fetchMarketDetails(ctx, marketID, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.validateMarketDetails(marketID, result)
+}
+
+// GetMarketDetailsOfType fetches a market whose type is already known from a single endpoint,
+// skipping the binary endpoint attempt for multi-outcome markets
+func (c *Client) GetMarketDetailsOfType(ctx context.Context, marketID string, categorical bool) (*MarketDetail, error) {
+	result, err := c.fetchMarketDetails(ctx, marketID, categorical)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.validateMarketDetails(marketID, result)
+}
+
+// fetchMarketDetails requests a market from the binary or categorical market endpoint
+func (c *Client) fetchMarketDetails(ctx context.Context, marketID string, categorical bool) (*MarketDetailResponse, error) {
 	path := fmt.Sprintf("/openapi/market/%s", marketID)
+	if categorical {
+		path = fmt.Sprintf("/openapi/market/categorical/%s", marketID)
+	}
+
 	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market details for market %s: %w", marketID, err)
@@ -20,20 +54,11 @@ func (c *Client) GetMarketDetails(ctx context.Context, marketID string) (*Market
 		return nil, fmt.Errorf("failed to decode market details response: %w", err)
 	}
 
-	// If errno=10200, it might be a categorical market - try that endpoint
-	if result.Errno == 10200 {
-		c.log.Debugf("Market %s not found as binary, trying categorical endpoint...", marketID)
-		categoricalPath := fmt.Sprintf("/openapi/market/categorical/%s", marketID)
-		resp, err = c.doRequest(ctx, "GET", categoricalPath, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get categorical market details for market %s: %w", marketID, err)
-		}
-
-		if err := c.decodeResponse(resp, &result); err != nil {
-			return nil, fmt.Errorf("failed to decode categorical market details response: %w", err)
-		}
-	}
+	return &result, nil
+}
 
+// validateMarketDetails checks a market detail response for API errors and missing tokens
+func (c *Client) validateMarketDetails(marketID string, result *MarketDetailResponse) (*MarketDetail, error) {
 	// Check for API errors
 	if result.Code != 0 || result.Errno != 0 {
 		return nil, fmt.Errorf("API error: code=%d, errno=%d, msg=%s", result.Code, result.Errno, result.Msg)
@@ -51,20 +76,16 @@ func (c *Client) GetMarketDetails(ctx context.Context, marketID string) (*Market
 		return nil, fmt.Errorf("market %s is not active", marketID)
 	}
 
-	// For multi-outcome markets (marketType 1), use the first child market's token
-	if marketData.MarketType == 1 {
+	// Multi-outcome markets (marketType 1) are tracked through their child markets' YES tokens
+	if marketData.IsCategorical() {
 		if len(marketData.ChildMarkets) == 0 {
 			return nil, fmt.Errorf("multi-outcome market %s has no child markets", marketID)
 		}
-		// Use the first child market's YES token ID
-		firstChild := marketData.ChildMarkets[0]
-		if firstChild.YesTokenID == "" {
-			return nil, fmt.Errorf("multi-outcome market %s first child has no YES token ID", marketID)
+		for _, child := range marketData.ChildMarkets {
+			if child.YesTokenID == "" {
+				return nil, fmt.Errorf("multi-outcome market %s child %d has no YES token ID", marketID, child.MarketID)
+			}
 		}
-		// Override the parent market's YesTokenID with the first child's token
-		marketData.YesTokenID = firstChild.YesTokenID
-		c.log.Infof("Multi-outcome market %s: automatically selected first outcome token %s (%s)",
-			marketID, firstChild.YesTokenID, firstChild.MarketTitle)
 	} else if marketData.MarketType == 0 {
 		// Binary market - require YES/NO tokens
 		if marketData.YesTokenID == "" {
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// BookDepthBand is how far from the best price resting orders count toward the book depth (5 cents)
+const BookDepthBand = 0.05
+
+// GetOrderBook fetches the order book of a specific token
+func (c *Client) GetOrderBook(ctx context.Context, tokenID string) (*OrderBook, error) {
+	path := fmt.Sprintf("/openapi/token/orderbook?token_id=%s", tokenID)
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order book for token %s: %w", tokenID, err)
+	}
+
+	var result OrderBookResponse
+	if err := c.decodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode order book response: %w", err)
+	}
+
+	if result.Code != 0 {
+		return nil, fmt.Errorf("API error: code=%d, msg=%s", result.Code, result.Msg)
+	}
+
+	c.log.Debugf("Retrieved order book for token %s: %d bids, %d asks", tokenID, len(result.Result.Bids), len(result.Result.Asks))
+
+	return &result.Result, nil
+}
+
+// BookSummary is the top of an order book and the depth near it. Prices and sizes are 0 for an empty side
+type BookSummary struct {
+	BestBid  float64
+	BestAsk  float64
+	BidSize  float64 // Shares at the best bid
+	AskSize  float64 // Shares at the best ask
+	BidDepth float64 // Notional of the bids within BookDepthBand of the best bid
+	AskDepth float64 // Notional of the asks within BookDepthBand of the best ask
+}
+
+// Summarize returns the best bid and ask of the book and the depth near them.
+// Levels are not assumed to be sorted
+func (b *OrderBook) Summarize() (*BookSummary, error) {
+	bids, err := parseLevels(b.Bids)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := parseLevels(b.Asks)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &BookSummary{}
+	for _, level := range bids {
+		if level.price > summary.BestBid {
+			summary.BestBid, summary.BidSize = level.price, level.size
+		}
+	}
+	for _, level := range asks {
+		if summary.BestAsk == 0 || level.price < summary.BestAsk {
+			summary.BestAsk, summary.AskSize = level.price, level.size
+		}
+	}
+
+	for _, level := range bids {
+		if level.price >= summary.BestBid-BookDepthBand {
+			summary.BidDepth += level.price * level.size
+		}
+	}
+	for _, level := range asks {
+		if level.price <= summary.BestAsk+BookDepthBand {
+			summary.AskDepth += level.price * level.size
+		}
+	}
+
+	return summary, nil
+}
+
+// bookLevel is a parsed order book level
+type bookLevel struct {
+	price float64
+	size  float64
+}
+
+// parseLevels converts order book levels to numbers, skipping empty levels
+func parseLevels(levels []OrderBookLevel) ([]bookLevel, error) {
+	parsed := make([]bookLevel, 0, len(levels))
+	for _, level := range levels {
+		price, err := strconv.ParseFloat(level.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order book price: %w", err)
+		}
+		size, err := strconv.ParseFloat(level.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order book size: %w", err)
+		}
+		if price <= 0 || size <= 0 {
+			continue
+		}
+		parsed = append(parsed, bookLevel{price: price, size: size})
+	}
+	return parsed, nil
+}
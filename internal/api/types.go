@@ -31,6 +31,20 @@ type MarketDetail struct {
 	ChildMarkets  []MarketDetail `json:"childMarkets"` // For multi-outcome markets
 }
 
+// IsCategorical reports whether the market is a multi-outcome market with child markets
+func (m *MarketDetail) IsCategorical() bool {
+	return m.MarketType == 1
+}
+
+// DefaultTokenID returns the token tracked when an alert has no explicit token:
+// the YES token for binary markets, or the first outcome's YES token for multi-outcome markets
+func (m *MarketDetail) DefaultTokenID() string {
+	if m.IsCategorical() && len(m.ChildMarkets) > 0 {
+		return m.ChildMarkets[0].YesTokenID
+	}
+	return m.YesTokenID
+}
+
 // MarketDetailResult wraps the data field in the result
 type MarketDetailResult struct {
 	Data MarketDetail `json:"data"`
@@ -59,3 +73,25 @@ type TokenPriceResponse struct {
 	Msg    string     `json:"msg"`
 	Result TokenPrice `json:"result"`
 }
+
+// OrderBookLevel is one price level of an order book
+type OrderBookLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// OrderBook represents the resting orders of a token
+type OrderBook struct {
+	Market    string           `json:"market"`
+	TokenID   string           `json:"tokenId"`
+	Timestamp int64            `json:"timestamp"`
+	Bids      []OrderBookLevel `json:"bids"`
+	Asks      []OrderBookLevel `json:"asks"`
+}
+
+// OrderBookResponse wraps the order book API response
+type OrderBookResponse struct {
+	Code   int       `json:"code"`
+	Msg    string    `json:"msg"`
+	Result OrderBook `json:"result"`
+}
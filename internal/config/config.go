@@ -37,9 +37,11 @@ type DatabaseConfig struct {
 
 // AppConfig holds application-level configuration
 type AppConfig struct {
-	PollInterval int
-	LogLevel     string
-	Timezone     string
+	PollInterval   int
+	AlertCooldown  int // Default seconds between notifications of the same alert
+	MonitorWorkers int // Markets checked concurrently in each monitoring cycle
+	LogLevel       string
+	Timezone       string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -49,6 +51,16 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid POLL_INTERVAL: %w", err)
 	}
 
+	alertCooldown, err := strconv.Atoi(getEnv("ALERT_COOLDOWN", "300"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALERT_COOLDOWN: %w", err)
+	}
+
+	monitorWorkers, err := strconv.Atoi(getEnv("MONITOR_WORKERS", "8"))
+	if err != nil || monitorWorkers < 1 {
+		return nil, fmt.Errorf("invalid MONITOR_WORKERS: must be a positive integer")
+	}
+
 	dbPort, err := strconv.Atoi(getEnv("DB_PORT", "5432"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid DB_PORT: %w", err)
@@ -71,9 +83,11 @@ func LoadConfig() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		App: AppConfig{
-			PollInterval: pollInterval,
-			LogLevel:     getEnv("LOG_LEVEL", "info"),
-			Timezone:     getEnv("TZ", "UTC"),
+			PollInterval:   pollInterval,
+			AlertCooldown:  alertCooldown,
+			MonitorWorkers: monitorWorkers,
+			LogLevel:       getEnv("LOG_LEVEL", "info"),
+			Timezone:       getEnv("TZ", "UTC"),
 		},
 	}
 
@@ -0,0 +1,226 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/api"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+	"github.com/qmitry/opinion-alert-bot/internal/telegram"
+	"github.com/sirupsen/logrus"
+)
+
+// consistencyCheckInterval is how often the outcome prices of markets with consistency alerts are compared
+const consistencyCheckInterval = time.Minute
+
+// legOffset is how far a leg's price must be from its fair price to be listed as off
+const legOffset = 0.01
+
+// ConsistencyChecker alerts when the outcome prices of a market stop adding up to $1: YES and NO
+// of a binary market, or the YES prices of all outcomes of a multi-outcome market
+type ConsistencyChecker struct {
+	prices *PriceChecker
+	log    *logrus.Logger
+
+	// Alerts that fired while their market is outside their band, by alert ID. An alert fires when its
+	// market leaves the band and again only after it came back, so drifting is kept in memory
+	driftingMu sync.Mutex
+	drifting   map[int64]bool
+}
+
+// NewConsistencyChecker creates a new consistency checker sharing the price checker's API client, notifier and cooldowns
+func NewConsistencyChecker(prices *PriceChecker, log *logrus.Logger) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		prices:   prices,
+		log:      log,
+		drifting: make(map[int64]bool),
+	}
+}
+
+// Start runs the consistency checker until the context is cancelled
+func (c *ConsistencyChecker) Start(ctx context.Context) {
+	ticker := time.NewTicker(consistencyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkMarkets(ctx)
+		}
+	}
+}
+
+// checkMarkets compares the outcome prices of every market watched by an active consistency alert
+func (c *ConsistencyChecker) checkMarkets(ctx context.Context) {
+	alerts, err := c.prices.storage.GetActiveAlerts(ctx)
+	if err != nil {
+		c.log.Errorf("Failed to get active alerts: %v", err)
+		return
+	}
+
+	// Group consistency alerts by market, skipping alerts snoozed from a notification
+	now := time.Now()
+	active := make(map[int64]bool)
+	alertsByMarket := make(map[string][]storage.Alert)
+	for _, alert := range alerts {
+		if alert.AlertType != storage.AlertTypeConsistency {
+			continue
+		}
+		active[alert.ID] = true
+		if !alert.IsMuted(now) {
+			alertsByMarket[alert.MarketID] = append(alertsByMarket[alert.MarketID], alert)
+		}
+	}
+
+	// Forget alerts that were deleted or paused since the last check
+	c.driftingMu.Lock()
+	for alertID := range c.drifting {
+		if !active[alertID] {
+			delete(c.drifting, alertID)
+		}
+	}
+	c.driftingMu.Unlock()
+
+	for marketID, marketAlerts := range alertsByMarket {
+		if ctx.Err() != nil {
+			return
+		}
+		c.checkMarket(ctx, marketID, marketAlerts)
+	}
+}
+
+// checkMarket fetches the price of every leg of a market and triggers the alerts whose band the sum left
+func (c *ConsistencyChecker) checkMarket(ctx context.Context, marketID string, alerts []storage.Alert) {
+	// The price checker keeps the market's metadata fresh, since consistency alerts are polled along
+	// with the others. Refreshing it here as well could report a status change twice
+	market, err := c.prices.storage.GetMarket(ctx, marketID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			c.log.Warnf("Failed to get market %s: %v", marketID, err)
+		}
+		return
+	}
+	if market.FetchedAt == nil {
+		return
+	}
+
+	// Prices of markets that stopped trading no longer have to add up
+	if market.StatusLabel(time.Now()) != "" {
+		return
+	}
+
+	legs, sum, ok := c.fetchLegs(ctx, market)
+	if !ok {
+		return
+	}
+
+	c.log.Debugf("Market %s: %d legs add up to %.4f", marketID, len(legs), sum)
+
+	for _, alert := range alerts {
+		drifted := math.Abs(sum-1)*100 >= alert.BandCents
+
+		c.driftingMu.Lock()
+		fired := c.drifting[alert.ID]
+		if !drifted {
+			delete(c.drifting, alert.ID)
+		}
+		c.driftingMu.Unlock()
+
+		if !drifted || fired {
+			continue
+		}
+
+		changePct := (sum - 1) * 100
+		allowed, err := c.prices.allowTrigger(ctx, &alert, "", changePct)
+		if err != nil {
+			c.log.Errorf("Failed to check cooldown for alert %d: %v", alert.ID, err)
+			continue
+		}
+		if !allowed {
+			continue
+		}
+
+		c.log.Infof("Consistency alert triggered for market %s: prices add up to %.4f (band: %g¢)", marketID, sum, alert.BandCents)
+
+		if err := c.prices.notifier.SendConsistencyAlert(ctx, &alert, market.Title, legs, sum); err != nil {
+			c.log.Errorf("Failed to send consistency alert: %v", err)
+			continue
+		}
+
+		// Only a sent alert waits for the market to return to its band, so one held back by
+		// its cooldown or a failed send is tried again on the next check
+		c.driftingMu.Lock()
+		c.drifting[alert.ID] = true
+		c.driftingMu.Unlock()
+	}
+}
+
+// fetchLegs fetches the current price of every leg of a market and their sum. Legs are sorted by how far
+// they are from their fair price, the price rescaled so that all legs add up to $1.
+// Returns false if the market has fewer than two legs or a price couldn't be fetched
+func (c *ConsistencyChecker) fetchLegs(ctx context.Context, market *storage.Market) ([]telegram.ConsistencyLeg, float64, bool) {
+	type leg struct {
+		label   string
+		tokenID string
+	}
+
+	var tokens []leg
+	if market.IsCategorical() {
+		for _, child := range market.ChildMarkets {
+			tokens = append(tokens, leg{label: child.Title, tokenID: child.YesTokenID})
+		}
+	} else if market.NoTokenID != "" {
+		yes, no := market.YesLabel, market.NoLabel
+		if yes == "" {
+			yes = "Yes"
+		}
+		if no == "" {
+			no = "No"
+		}
+		tokens = []leg{{label: yes, tokenID: market.YesTokenID}, {label: no, tokenID: market.NoTokenID}}
+	}
+
+	if len(tokens) < 2 {
+		c.log.Debugf("Market %s has no outcome prices to compare", market.MarketID)
+		return nil, 0, false
+	}
+
+	legs := make([]telegram.ConsistencyLeg, 0, len(tokens))
+	sum := 0.0
+	for _, token := range tokens {
+		tokenPrice, err := c.prices.apiClient.GetTokenPrice(ctx, token.tokenID)
+		if err != nil {
+			c.log.Warnf("Failed to get price of %s in market %s: %v", token.label, market.MarketID, err)
+			return nil, 0, false
+		}
+
+		price, err := api.ParseTokenPrice(tokenPrice.Price)
+		if err != nil {
+			c.log.Warnf("Failed to parse price of %s in market %s: %v", token.label, market.MarketID, err)
+			return nil, 0, false
+		}
+
+		legs = append(legs, telegram.ConsistencyLeg{Label: token.label, Price: price})
+		sum += price
+	}
+
+	if sum <= 0 {
+		return nil, 0, false
+	}
+
+	for i := range legs {
+		legs[i].Fair = legs[i].Price / sum
+		legs[i].Off = math.Abs(legs[i].Price-legs[i].Fair) >= legOffset
+	}
+	sort.SliceStable(legs, func(i, j int) bool {
+		return math.Abs(legs[i].Price-legs[i].Fair) > math.Abs(legs[j].Price-legs[j].Fair)
+	})
+
+	return legs, sum, true
+}
@@ -0,0 +1,208 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+	"github.com/qmitry/opinion-alert-bot/internal/telegram"
+	"github.com/sirupsen/logrus"
+)
+
+// digestCheckInterval is how often the digest scheduler looks for users with a digest due
+const digestCheckInterval = time.Minute
+
+// maxDigestMoves is the number of biggest moves listed in a digest
+const maxDigestMoves = 5
+
+// maxDigestCatchUp is how far before its own period a digest reaches back for market prices when earlier
+// digests were held back by quiet hours or missed. Alerts of those periods are always included
+const maxDigestCatchUp = 24 * time.Hour
+
+// DigestScheduler compiles the hourly and daily digests of users in digest mode
+type DigestScheduler struct {
+	storage *storage.Storage
+	bot     *telegram.Bot
+	log     *logrus.Logger
+}
+
+// NewDigestScheduler creates a new digest scheduler instance
+func NewDigestScheduler(storage *storage.Storage, bot *telegram.Bot, log *logrus.Logger) *DigestScheduler {
+	return &DigestScheduler{
+		storage: storage,
+		bot:     bot,
+		log:     log,
+	}
+}
+
+// Start runs the digest scheduler until the context is cancelled
+func (d *DigestScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.sendDueDigests(ctx)
+		}
+	}
+}
+
+// sendDueDigests compiles a digest for every user whose current period has ended
+func (d *DigestScheduler) sendDueDigests(ctx context.Context) {
+	users, err := d.storage.GetDigestUsers(ctx)
+	if err != nil {
+		d.log.Errorf("Failed to get digest users: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		periodEnd := digestPeriodEnd(user.DigestMode, now.In(user.Location()))
+		if user.LastDigestAt != nil && !user.LastDigestAt.Before(periodEnd) {
+			continue
+		}
+
+		// Digests wait for quiet hours to end like any other held alert
+		if user.InQuietHours(now) {
+			continue
+		}
+
+		if err := d.sendDigest(ctx, &user, periodEnd); err != nil {
+			d.log.Errorf("Failed to send digest to user %d: %v", user.TelegramID, err)
+			continue
+		}
+
+		if err := d.storage.UpdateLastDigestAt(ctx, user.ID, periodEnd); err != nil {
+			d.log.Errorf("Failed to record digest of user %d: %v", user.TelegramID, err)
+		}
+	}
+}
+
+// sendDigest compiles and sends the digest of a user for the period ending at periodEnd, along with
+// the alerts of earlier periods whose digest was held back or missed.
+// Nothing is sent when no alert triggered since the last digest
+func (d *DigestScheduler) sendDigest(ctx context.Context, user *storage.User, periodEnd time.Time) error {
+	periodStart := digestPeriodStart(user.DigestMode, periodEnd, user.LastDigestAt)
+
+	history, err := d.storage.GetDigestAlertHistory(ctx, user.ID, periodEnd)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return nil
+	}
+
+	alerts, err := d.storage.GetAlertsByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// Open/close/high/low of every token the user watches
+	alertsByID := make(map[int64]*storage.Alert, len(alerts))
+	seenTokens := make(map[string]bool)
+	var markets []telegram.DigestMarket
+	for i := range alerts {
+		alert := &alerts[i]
+		alertsByID[alert.ID] = alert
+
+		if !alert.IsActive || alert.TokenID == nil || seenTokens[*alert.TokenID] {
+			continue
+		}
+		seenTokens[*alert.TokenID] = true
+
+		ohlc, err := d.storage.GetPriceOHLC(ctx, *alert.TokenID, periodStart, periodEnd)
+		if err != nil {
+			d.log.Debugf("No prices for token %s in digest period: %v", *alert.TokenID, err)
+			continue
+		}
+		markets = append(markets, telegram.DigestMarket{Label: telegram.AlertLabel(alert), OHLC: *ohlc})
+	}
+
+	// Biggest moves among the collected triggers
+	moves := make([]telegram.HistoryEntry, 0, len(history))
+	for _, h := range history {
+		entry := telegram.HistoryEntry{
+			TriggeredAt:   h.TriggeredAt,
+			PreviousPrice: h.PreviousPrice,
+			CurrentPrice:  h.CurrentPrice,
+			ChangePct:     h.ChangePct,
+		}
+		if alert, ok := alertsByID[h.AlertID]; ok {
+			entry.Label = telegram.AlertLabel(alert)
+		}
+		moves = append(moves, entry)
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		return math.Abs(moves[i].ChangePct) > math.Abs(moves[j].ChangePct)
+	})
+	if len(moves) > maxDigestMoves {
+		moves = moves[:maxDigestMoves]
+	}
+
+	message := telegram.FormatDigest(user.DigestMode, markets, moves, len(history), user.Location())
+	if err := d.bot.SendAlertNotification(ctx, user.TelegramID, message, nil); err != nil {
+		return err
+	}
+
+	for _, h := range history {
+		if err := d.storage.MarkMessageSent(ctx, h.ID); err != nil {
+			d.log.Warnf("Failed to mark message as sent for history %d: %v", h.ID, err)
+		}
+	}
+
+	d.log.Infof("Sent %s digest with %d alerts to user %d", user.DigestMode, len(history), user.TelegramID)
+	return nil
+}
+
+// digestPeriodEnd returns the end of the latest digest period that ended at or before now
+// Hourly periods end on the hour, daily periods at storage.DailyDigestHour, both in now's timezone
+func digestPeriodEnd(mode string, now time.Time) time.Time {
+	year, month, day := now.Date()
+	if mode == storage.DigestHourly {
+		return time.Date(year, month, day, now.Hour(), 0, 0, 0, now.Location())
+	}
+
+	end := time.Date(year, month, day, storage.DailyDigestHour, 0, 0, 0, now.Location())
+	if now.Before(end) {
+		end = end.AddDate(0, 0, -1)
+	}
+	return end
+}
+
+// digestPeriodStart returns the start of the span covered by the digest ending at periodEnd: its own period,
+// extended back to the last digest sent when earlier ones were held back or missed, by up to maxDigestCatchUp
+func digestPeriodStart(mode string, periodEnd time.Time, lastDigestAt *time.Time) time.Time {
+	start := periodEnd.AddDate(0, 0, -1)
+	if mode == storage.DigestHourly {
+		start = periodEnd.Add(-time.Hour)
+	}
+	if lastDigestAt == nil || !lastDigestAt.Before(start) {
+		return start
+	}
+
+	if earliest := start.Add(-maxDigestCatchUp); lastDigestAt.Before(earliest) {
+		return earliest
+	}
+	return lastDigestAt.In(periodEnd.Location())
+}
+
+// digestRetention returns how long price history must be kept to compile the digests of the given users,
+// covering their latest period and the earlier ones a held back or missed digest catches up on
+func digestRetention(users []storage.User) time.Duration {
+	var retention time.Duration
+	for _, user := range users {
+		period := 24 * time.Hour
+		if user.DigestMode == storage.DigestHourly {
+			period = time.Hour
+		}
+		if period+maxDigestCatchUp > retention {
+			retention = period + maxDigestCatchUp
+		}
+	}
+	return retention
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+func TestDigestPeriodEnd(t *testing.T) {
+	moscow := time.FixedZone("UTC+3", 3*60*60)
+
+	tests := []struct {
+		name string
+		mode string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "hourly ends on the hour",
+			mode: storage.DigestHourly,
+			now:  time.Date(2026, 3, 10, 14, 37, 12, 0, time.UTC),
+			want: time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "hourly exactly on the hour",
+			mode: storage.DigestHourly,
+			now:  time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily before the digest hour ends yesterday",
+			mode: storage.DigestDaily,
+			now:  time.Date(2026, 3, 10, storage.DailyDigestHour-1, 59, 0, 0, time.UTC),
+			want: time.Date(2026, 3, 9, storage.DailyDigestHour, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily at the digest hour ends today",
+			mode: storage.DigestDaily,
+			now:  time.Date(2026, 3, 10, storage.DailyDigestHour, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 3, 10, storage.DailyDigestHour, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily across a month boundary",
+			mode: storage.DigestDaily,
+			now:  time.Date(2026, 4, 1, 3, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 3, 31, storage.DailyDigestHour, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily in the user's timezone",
+			mode: storage.DigestDaily,
+			now:  time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC).In(moscow),
+			want: time.Date(2026, 3, 10, storage.DailyDigestHour, 0, 0, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := digestPeriodEnd(tt.mode, tt.now)
+			if !got.Equal(tt.want) {
+				t.Errorf("digestPeriodEnd(%s, %v) = %v, want %v", tt.mode, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestPeriodStart(t *testing.T) {
+	end := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := end.Add(d)
+		return &v
+	}
+
+	tests := []struct {
+		name         string
+		mode         string
+		lastDigestAt *time.Time
+		want         time.Time
+	}{
+		{
+			name: "hourly without a previous digest",
+			mode: storage.DigestHourly,
+			want: end.Add(-time.Hour),
+		},
+		{
+			name: "daily without a previous digest",
+			mode: storage.DigestDaily,
+			want: end.AddDate(0, 0, -1),
+		},
+		{
+			name:         "hourly after the previous period's digest",
+			mode:         storage.DigestHourly,
+			lastDigestAt: at(-time.Hour),
+			want:         end.Add(-time.Hour),
+		},
+		{
+			name:         "hourly catching up on digests held back by quiet hours",
+			mode:         storage.DigestHourly,
+			lastDigestAt: at(-8 * time.Hour),
+			want:         end.Add(-8 * time.Hour),
+		},
+		{
+			name:         "hourly catch-up is capped",
+			mode:         storage.DigestHourly,
+			lastDigestAt: at(-72 * time.Hour),
+			want:         end.Add(-time.Hour - maxDigestCatchUp),
+		},
+		{
+			name:         "daily catching up on a missed digest",
+			mode:         storage.DigestDaily,
+			lastDigestAt: at(-48 * time.Hour),
+			want:         end.Add(-48 * time.Hour),
+		},
+		{
+			name:         "daily catch-up is capped",
+			mode:         storage.DigestDaily,
+			lastDigestAt: at(-5 * 24 * time.Hour),
+			want:         end.AddDate(0, 0, -1).Add(-maxDigestCatchUp),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := digestPeriodStart(tt.mode, end, tt.lastDigestAt)
+			if !got.Equal(tt.want) {
+				t.Errorf("digestPeriodStart(%s, %v, %v) = %v, want %v", tt.mode, end, tt.lastDigestAt, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/api"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// marketCacheTTL is how long cached market metadata is used before it is fetched again
+	marketCacheTTL = time.Hour
+	// closedMarketTTL is the shorter TTL of markets past their cutoff, so resolution is noticed quickly
+	closedMarketTTL = 5 * time.Minute
+)
+
+// MarketCache serves market metadata from the markets table, refreshing entries older than the TTL
+type MarketCache struct {
+	apiClient *api.Client
+	storage   *storage.Storage
+	notifier  *Notifier
+	ttl       time.Duration
+	log       *logrus.Logger
+}
+
+// NewMarketCache creates a new market metadata cache
+func NewMarketCache(apiClient *api.Client, storage *storage.Storage, notifier *Notifier, log *logrus.Logger) *MarketCache {
+	return &MarketCache{
+		apiClient: apiClient,
+		storage:   storage,
+		notifier:  notifier,
+		ttl:       marketCacheTTL,
+		log:       log,
+	}
+}
+
+// Get returns the metadata of a market, fetching it only when it isn't cached or has expired.
+// A stale entry is still returned if the refresh fails. Users are notified when a refresh
+// shows that the market's status changed
+func (mc *MarketCache) Get(ctx context.Context, marketID string) (*storage.Market, error) {
+	cached, err := mc.storage.GetMarket(ctx, marketID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	now := time.Now()
+	if cached != nil && !mc.isStale(cached, now) {
+		return cached, nil
+	}
+
+	// The market type is known once fetched, so the binary endpoint is skipped for multi-outcome markets
+	var details *api.MarketDetail
+	if cached != nil && cached.FetchedAt != nil {
+		details, err = mc.apiClient.GetMarketDetailsOfType(ctx, marketID, cached.IsCategorical())
+	} else {
+		details, err = mc.apiClient.GetMarketDetails(ctx, marketID)
+	}
+	if err != nil {
+		if cached != nil {
+			mc.log.Warnf("Failed to refresh market %s, using cached details: %v", marketID, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	market := marketFromDetails(marketID, details)
+	if err := mc.storage.UpsertMarket(ctx, market); err != nil {
+		mc.log.Warnf("Failed to cache market %s: %v", marketID, err)
+	}
+
+	if cached != nil && cached.FetchedAt != nil && market.StatusChanged(cached, now) {
+		if err := mc.notifier.SendMarketStatusChange(ctx, cached, market); err != nil {
+			mc.log.Errorf("Failed to send status change of market %s: %v", marketID, err)
+		}
+	}
+
+	return market, nil
+}
+
+// isStale reports whether a cached market must be fetched again. Markets past their cutoff
+// are refreshed more often until they resolve
+func (mc *MarketCache) isStale(market *storage.Market, now time.Time) bool {
+	if market.IsStale(mc.ttl, now) {
+		return true
+	}
+	if market.CutoffAt != nil && now.After(*market.CutoffAt) && !market.IsResolved() {
+		return market.IsStale(closedMarketTTL, now)
+	}
+	return false
+}
+
+// marketFromDetails converts market details from the Opinion API into a stored market
+func marketFromDetails(marketID string, details *api.MarketDetail) *storage.Market {
+	market := &storage.Market{
+		MarketID:       marketID,
+		Title:          details.MarketTitle,
+		MarketType:     details.MarketType,
+		Status:         details.Status,
+		StatusEnum:     details.StatusEnum,
+		CutoffAt:       unixTime(details.CutoffAt),
+		ResolvedAt:     unixTime(details.ResolvedAt),
+		YesLabel:       details.YesLabel,
+		NoLabel:        details.NoLabel,
+		YesTokenID:     details.YesTokenID,
+		NoTokenID:      details.NoTokenID,
+		QuoteToken:     details.QuoteToken,
+		ResultTokenID:  details.ResultTokenID,
+		DefaultTokenID: details.DefaultTokenID(),
+	}
+
+	for _, child := range details.ChildMarkets {
+		market.ChildMarkets = append(market.ChildMarkets, storage.MarketChild{
+			MarketID:      child.MarketID,
+			Title:         child.MarketTitle,
+			Status:        child.Status,
+			YesTokenID:    child.YesTokenID,
+			NoTokenID:     child.NoTokenID,
+			ResultTokenID: child.ResultTokenID,
+		})
+	}
+
+	return market
+}
+
+// unixTime converts an Opinion API timestamp in seconds or milliseconds, nil if unset
+func unixTime(ts int64) *time.Time {
+	if ts <= 0 {
+		return nil
+	}
+
+	t := time.Unix(ts, 0)
+	if ts > 1e12 {
+		t = time.UnixMilli(ts)
+	}
+	return &t
+}
@@ -2,44 +2,81 @@ package monitor
 
 import (
 	"context"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/qmitry/opinion-alert-bot/internal/api"
+	"github.com/qmitry/opinion-alert-bot/internal/config"
 	"github.com/qmitry/opinion-alert-bot/internal/storage"
 	"github.com/qmitry/opinion-alert-bot/internal/telegram"
 	"github.com/sirupsen/logrus"
 )
 
+// minPriceRetention is the shortest price history kept regardless of configured windows
+const minPriceRetention = 5 * time.Minute
+
+// maxCheckJitter is the longest random delay before each market check, spreading
+// requests to the Opinion API instead of sending them in bursts
+const maxCheckJitter = 250 * time.Millisecond
+
 // Monitor represents the main monitoring service
 type Monitor struct {
 	storage      *storage.Storage
 	apiClient    *api.Client
+	notifier     *Notifier
 	priceChecker *PriceChecker
+	digests      *DigestScheduler
+	outbox       *Outbox
+	reminders    *ReminderScheduler
+	consistency  *ConsistencyChecker
 	pollInterval time.Duration
+	workers      int
 	log          *logrus.Logger
+
+	// Cycles that took longer than the poll interval since start
+	overruns int
 }
 
 // NewMonitor creates a new monitor instance
-func NewMonitor(storage *storage.Storage, apiClient *api.Client, bot *telegram.Bot, pollInterval int, log *logrus.Logger) *Monitor {
+func NewMonitor(storage *storage.Storage, apiClient *api.Client, bot *telegram.Bot, cfg config.AppConfig, log *logrus.Logger) *Monitor {
 	notifier := NewNotifier(bot, storage, log)
-	priceChecker := NewPriceChecker(apiClient, storage, notifier, log)
+	priceChecker := NewPriceChecker(apiClient, storage, notifier, time.Duration(cfg.AlertCooldown)*time.Second, log)
 
 	return &Monitor{
 		storage:      storage,
 		apiClient:    apiClient,
+		notifier:     notifier,
 		priceChecker: priceChecker,
-		pollInterval: time.Duration(pollInterval) * time.Second,
+		digests:      NewDigestScheduler(storage, bot, log),
+		outbox:       NewOutbox(storage, notifier, log),
+		reminders:    NewReminderScheduler(storage, apiClient, bot, log),
+		consistency:  NewConsistencyChecker(priceChecker, log),
+		pollInterval: time.Duration(cfg.PollInterval) * time.Second,
+		workers:      cfg.MonitorWorkers,
 		log:          log,
 	}
 }
 
 // Start begins the monitoring loop
 func (m *Monitor) Start(ctx context.Context) error {
-	m.log.Infof("Starting market monitor (poll interval: %v)", m.pollInterval)
+	m.log.Infof("Starting market monitor (poll interval: %v, workers: %d)", m.pollInterval, m.workers)
 
 	ticker := time.NewTicker(m.pollInterval)
 	defer ticker.Stop()
 
+	// Compile hourly and daily digests alongside the price checks
+	go m.digests.Start(ctx)
+
+	// Retry notifications that failed to send
+	go m.outbox.Start(ctx)
+
+	// Remind users of markets nearing their trading cutoff
+	go m.reminders.Start(ctx)
+
+	// Compare the outcome prices of markets with consistency alerts
+	go m.consistency.Start(ctx)
+
 	// Run initial check immediately
 	m.runMonitoringCycle(ctx)
 
@@ -57,6 +94,10 @@ func (m *Monitor) Start(ctx context.Context) error {
 // runMonitoringCycle performs one monitoring cycle
 func (m *Monitor) runMonitoringCycle(ctx context.Context) {
 	m.log.Debug("Starting monitoring cycle...")
+	start := time.Now()
+
+	// Deliver alerts held back during quiet hours that have since ended
+	m.notifier.DeliverDeferredAlerts(ctx)
 
 	// Get all active alerts
 	alerts, err := m.storage.GetActiveAlerts(ctx)
@@ -85,24 +126,107 @@ func (m *Monitor) runMonitoringCycle(ctx context.Context) {
 		alertsByMarket[alert.MarketID] = append(alertsByMarket[alert.MarketID], alert)
 	}
 
-	// Check each market
+	// Check markets concurrently, giving up on the rest once the next cycle is due
+	m.checkMarkets(ctx, markets, alertsByMarket)
+
+	if elapsed := time.Since(start); elapsed > m.pollInterval {
+		m.overruns++
+		m.log.Warnf("Monitoring cycle took %v, longer than the poll interval of %v (%d overruns so far)", elapsed.Round(time.Millisecond), m.pollInterval, m.overruns)
+	}
+
+	// Cleanup price data no longer needed by any alert's lookback window or digest
+	retention := m.priceRetention(alerts)
+	if digestUsers, err := m.storage.GetDigestUsers(ctx); err != nil {
+		m.log.Warnf("Failed to get digest users: %v", err)
+	} else if r := digestRetention(digestUsers); r > retention {
+		retention = r
+	}
+	if err := m.storage.CleanupOldPrices(ctx, retention); err != nil {
+		m.log.Warnf("Failed to cleanup old prices: %v", err)
+	}
+	if err := m.storage.CleanupOldVolumes(ctx, retention); err != nil {
+		m.log.Warnf("Failed to cleanup old volumes: %v", err)
+	}
+	if err := m.storage.CleanupOldBooks(ctx, retention); err != nil {
+		m.log.Warnf("Failed to cleanup old book snapshots: %v", err)
+	}
+
+	m.log.Debug("Monitoring cycle completed")
+}
+
+// checkMarkets checks the given markets with a bounded pool of workers. Markets not
+// checked before the poll interval elapses are skipped until the next cycle
+func (m *Monitor) checkMarkets(ctx context.Context, markets []string, alertsByMarket map[string][]storage.Alert) {
+	cycleCtx, cancel := context.WithTimeout(ctx, m.pollInterval)
+	defer cancel()
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for marketID := range jobs {
+				m.checkMarket(cycleCtx, marketID, alertsByMarket[marketID])
+			}
+		}()
+	}
+
+	watched, skipped := 0, 0
 	for _, marketID := range markets {
-		marketAlerts := alertsByMarket[marketID]
-		if len(marketAlerts) == 0 {
+		if len(alertsByMarket[marketID]) == 0 {
 			continue
 		}
-
-		// Check market price and trigger alerts if needed
-		if err := m.priceChecker.CheckMarketPrice(ctx, marketID, marketAlerts); err != nil {
-			m.log.Warnf("Error checking market %s: %v", marketID, err)
-			continue
+		watched++
+
+		if cycleCtx.Err() == nil {
+			select {
+			case jobs <- marketID:
+				continue
+			case <-cycleCtx.Done():
+			}
 		}
+		skipped++
 	}
+	close(jobs)
+	wg.Wait()
 
-	// Cleanup old price data (older than 5 minutes)
-	if err := m.storage.CleanupOldPrices(ctx, 5*time.Minute); err != nil {
-		m.log.Warnf("Failed to cleanup old prices: %v", err)
+	if skipped > 0 {
+		m.log.Warnf("Cycle deadline reached, skipped %d of %d markets", skipped, watched)
 	}
+}
 
-	m.log.Debug("Monitoring cycle completed")
+// checkMarket checks one market after a random delay, so concurrent workers don't hit the API in bursts
+func (m *Monitor) checkMarket(ctx context.Context, marketID string, alerts []storage.Alert) {
+	jitter := time.NewTimer(time.Duration(rand.Int63n(int64(maxCheckJitter))))
+	defer jitter.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-jitter.C:
+	}
+
+	// Check market price and trigger alerts if needed
+	if err := m.priceChecker.CheckMarketPrice(ctx, marketID, alerts); err != nil {
+		m.log.Warnf("Error checking market %s: %v", marketID, err)
+	}
+}
+
+// priceRetention returns how long price history must be kept to serve the longest
+// lookback window among the given alerts, including the lookup tolerance and one poll of slack
+func (m *Monitor) priceRetention(alerts []storage.Alert) time.Duration {
+	retention := minPriceRetention
+	for _, alert := range alerts {
+		window := alert.Window()
+		needed := window + window/6 + m.pollInterval
+		// Adaptive alerts seed their volatility estimate from the stored history after a restart
+		if alert.ThresholdUnit == storage.ThresholdUnitSigma {
+			needed = max(needed, volatilityLookback)
+		}
+		if needed > retention {
+			retention = needed
+		}
+	}
+	return retention
 }
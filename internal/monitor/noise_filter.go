@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// pendingMove is a spike that hasn't persisted for its alert's confirmation polls yet
+type pendingMove struct {
+	polls         int
+	previousPrice float64
+	currentPrice  float64
+	changePct     float64
+}
+
+// tradeTooSmall reports whether the trade that set a token's price is smaller than the alert's minimum trade size
+func tradeTooSmall(alert *storage.Alert, trade *storage.TokenPrice) bool {
+	return alert.MinTradeSize > 0 && trade.Size < alert.MinTradeSize
+}
+
+// confirmMove counts a poll on which the alert's move held and reports whether it has now held
+// for the alert's confirmation polls. Streaks are kept in memory, so they start over after a restart
+func (pc *PriceChecker) confirmMove(alert *storage.Alert, previousPrice, currentPrice, changePct float64) bool {
+	if alert.ConfirmPolls <= 1 {
+		return true
+	}
+
+	pc.pendingMu.Lock()
+	defer pc.pendingMu.Unlock()
+
+	move, ok := pc.pendingMoves[alert.ID]
+	if !ok {
+		move = &pendingMove{}
+		pc.pendingMoves[alert.ID] = move
+	}
+	move.polls++
+	move.previousPrice, move.currentPrice, move.changePct = previousPrice, currentPrice, changePct
+
+	if move.polls < alert.ConfirmPolls {
+		return false
+	}
+
+	delete(pc.pendingMoves, alert.ID)
+	return true
+}
+
+// endMove ends the alert's streak of polls once its move no longer holds, recording the move
+// as suppressed if it didn't persist long enough to be confirmed
+func (pc *PriceChecker) endMove(ctx context.Context, alert *storage.Alert) {
+	pc.pendingMu.Lock()
+	move, ok := pc.pendingMoves[alert.ID]
+	delete(pc.pendingMoves, alert.ID)
+	pc.pendingMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	pc.suppress(ctx, alert, move.previousPrice, move.currentPrice, move.changePct,
+		fmt.Sprintf("move held for %d of %d polls", move.polls, alert.ConfirmPolls))
+}
+
+// suppress records a trigger held back by the alert's noise filters. At most one suppressed trigger
+// is recorded per cooldown period, so a filter holding back the same move on every poll doesn't flood the history
+func (pc *PriceChecker) suppress(ctx context.Context, alert *storage.Alert, previousPrice, currentPrice, changePct float64, reason string) {
+	pc.log.Debugf("Alert %d suppressed by noise filter: %s", alert.ID, reason)
+
+	last, err := pc.storage.GetLastAlertHistory(ctx, alert.ID, true)
+	if err == nil && time.Since(last.TriggeredAt) < alert.Cooldown(pc.defaultCooldown) {
+		return
+	}
+
+	if err := pc.storage.CreateSuppressedAlertHistory(ctx, alert.ID, alert.MarketID, previousPrice, currentPrice, changePct, reason); err != nil {
+		pc.log.Errorf("Failed to record suppressed trigger of alert %d: %v", alert.ID, err)
+	}
+}
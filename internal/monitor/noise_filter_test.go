@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+func TestTradeTooSmall(t *testing.T) {
+	tests := []struct {
+		name         string
+		minTradeSize float64
+		size         float64
+		want         bool
+	}{
+		{name: "no minimum", minTradeSize: 0, size: 1, want: false},
+		{name: "below the minimum", minTradeSize: 100, size: 99, want: true},
+		{name: "at the minimum", minTradeSize: 100, size: 100, want: false},
+		{name: "above the minimum", minTradeSize: 100, size: 250, want: false},
+		{name: "trade without a size", minTradeSize: 100, size: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := &storage.Alert{MinTradeSize: tt.minTradeSize}
+			trade := &storage.TokenPrice{Size: tt.size}
+			if got := tradeTooSmall(alert, trade); got != tt.want {
+				t.Errorf("tradeTooSmall(min %g, size %g) = %v, want %v", tt.minTradeSize, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmMove(t *testing.T) {
+	tests := []struct {
+		name         string
+		confirmPolls int
+		polls        int    // Consecutive polls the move held on
+		want         []bool // confirmMove result of each poll
+		wantPending  int    // Polls of the streak left pending afterwards, 0 if none
+	}{
+		{name: "no confirmation", confirmPolls: 0, polls: 1, want: []bool{true}},
+		{name: "single poll", confirmPolls: 1, polls: 2, want: []bool{true, true}},
+		{name: "confirmed on the last poll", confirmPolls: 3, polls: 3, want: []bool{false, false, true}},
+		{name: "streak starts over once confirmed", confirmPolls: 2, polls: 3, want: []bool{false, true, false}, wantPending: 1},
+		{name: "not yet confirmed", confirmPolls: 3, polls: 2, want: []bool{false, false}, wantPending: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := &PriceChecker{pendingMoves: make(map[int64]*pendingMove)}
+			alert := &storage.Alert{ID: 1, ConfirmPolls: tt.confirmPolls}
+
+			for i := 0; i < tt.polls; i++ {
+				current := 0.60 + float64(i)*0.01
+				if got := pc.confirmMove(alert, 0.50, current, (current-0.50)/0.50*100); got != tt.want[i] {
+					t.Errorf("poll %d: confirmMove() = %v, want %v", i+1, got, tt.want[i])
+				}
+			}
+
+			move, pending := pc.pendingMoves[alert.ID]
+			if tt.wantPending == 0 {
+				if pending {
+					t.Errorf("move still pending after %d polls", move.polls)
+				}
+				return
+			}
+			if !pending {
+				t.Fatalf("no pending move, want %d polls", tt.wantPending)
+			}
+			if move.polls != tt.wantPending {
+				t.Errorf("pending polls = %d, want %d", move.polls, tt.wantPending)
+			}
+
+			// The pending move holds the latest prices, which are recorded if it's suppressed
+			if want := 0.60 + float64(tt.polls-1)*0.01; move.currentPrice != want {
+				t.Errorf("pending current price = %g, want %g", move.currentPrice, want)
+			}
+		})
+	}
+}
+
+func TestConfirmMoveKeepsAlertsApart(t *testing.T) {
+	pc := &PriceChecker{pendingMoves: make(map[int64]*pendingMove)}
+	first := &storage.Alert{ID: 1, ConfirmPolls: 2}
+	second := &storage.Alert{ID: 2, ConfirmPolls: 2}
+
+	if pc.confirmMove(first, 0.50, 0.60, 20) {
+		t.Fatal("first alert confirmed on its first poll")
+	}
+	if pc.confirmMove(second, 0.50, 0.60, 20) {
+		t.Fatal("second alert confirmed by the first alert's poll")
+	}
+	if !pc.confirmMove(first, 0.50, 0.61, 22) {
+		t.Error("first alert not confirmed on its second poll")
+	}
+}
+
+func TestEndMoveWithoutPendingMove(t *testing.T) {
+	// Nothing is suppressed, so no storage is needed
+	pc := &PriceChecker{pendingMoves: make(map[int64]*pendingMove)}
+	pc.endMove(context.Background(), &storage.Alert{ID: 1, ConfirmPolls: 3})
+
+	if len(pc.pendingMoves) != 0 {
+		t.Errorf("pending moves = %d, want 0", len(pc.pendingMoves))
+	}
+}
@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"time"
 
 	"github.com/qmitry/opinion-alert-bot/internal/storage"
 	"github.com/qmitry/opinion-alert-bot/internal/telegram"
@@ -24,8 +25,52 @@ func NewNotifier(bot *telegram.Bot, storage *storage.Storage, log *logrus.Logger
 	}
 }
 
-// SendPriceAlert sends a price spike alert to a user
-func (n *Notifier) SendPriceAlert(ctx context.Context, alert *storage.Alert, marketTitle string, previousPrice, currentPrice, changePct float64) error {
+// SendPriceAlert sends a price spike alert to a user. zScore is the move in standard deviations, 0 unless the alert is adaptive
+func (n *Notifier) SendPriceAlert(ctx context.Context, alert *storage.Alert, marketTitle string, previousPrice, currentPrice, changePct, zScore float64) error {
+	var message string
+	switch alert.AlertType {
+	case storage.AlertTypeCrossing:
+		message = telegram.FormatCrossingNotification(alert, marketTitle, previousPrice, currentPrice)
+	default:
+		message = telegram.FormatAlertNotification(alert, marketTitle, previousPrice, currentPrice, changePct, zScore)
+	}
+
+	return n.sendAlert(ctx, alert, message, previousPrice, currentPrice, changePct)
+}
+
+// SendVolumeAlert sends a volume alert to a user. The prices are the last price before and at the trigger
+func (n *Notifier) SendVolumeAlert(ctx context.Context, alert *storage.Alert, marketTitle string, trigger telegram.VolumeTrigger, previousPrice, currentPrice float64) error {
+	changePct := 0.0
+	if previousPrice > 0 {
+		changePct = ((currentPrice - previousPrice) / previousPrice) * 100
+	}
+
+	message := telegram.FormatVolumeNotification(alert, marketTitle, trigger)
+	return n.sendAlert(ctx, alert, message, previousPrice, currentPrice, changePct)
+}
+
+// SendBookAlert sends a spread or liquidity alert to a user. Prices are taken from the midpoints of the books
+func (n *Notifier) SendBookAlert(ctx context.Context, alert *storage.Alert, marketTitle string, previous, current *storage.BookSnapshot) error {
+	previousPrice, currentPrice := previous.Mid(), current.Mid()
+	changePct := 0.0
+	if previousPrice > 0 {
+		changePct = ((currentPrice - previousPrice) / previousPrice) * 100
+	}
+
+	message := telegram.FormatBookNotification(alert, marketTitle, current)
+	return n.sendAlert(ctx, alert, message, previousPrice, currentPrice, changePct)
+}
+
+// SendConsistencyAlert sends an alert that a market's outcome prices no longer add up to $1.
+// The history records $1 as the previous and the sum as the current price
+func (n *Notifier) SendConsistencyAlert(ctx context.Context, alert *storage.Alert, marketTitle string, legs []telegram.ConsistencyLeg, sum float64) error {
+	message := telegram.FormatConsistencyNotification(alert, marketTitle, legs, sum)
+	return n.sendAlert(ctx, alert, message, 1, sum, (sum-1)*100)
+}
+
+// sendAlert records a triggered alert and sends its message, unless the user's quiet hours
+// or digest hold it back
+func (n *Notifier) sendAlert(ctx context.Context, alert *storage.Alert, message string, previousPrice, currentPrice, changePct float64) error {
 	// Get user to find their chat ID
 	user, err := n.storage.GetUserByID(ctx, alert.UserID)
 	if err != nil {
@@ -33,34 +78,216 @@ func (n *Notifier) SendPriceAlert(ctx context.Context, alert *storage.Alert, mar
 		return err
 	}
 
-	// Create alert history record
-	history, err := n.storage.CreateAlertHistory(ctx, alert.ID, alert.MarketID, previousPrice, currentPrice, changePct)
+	// Urgent alerts always go out immediately; others are collected for the user's digest
+	// or held for the quiet hours summary. Only spike and crossing alerts report a price move,
+	// so the change of other alert types never makes them urgent
+	urgent := false
+	switch alert.AlertType {
+	case storage.AlertTypeSpike, storage.AlertTypeCrossing:
+		urgent = user.IsUrgent(changePct)
+	}
+
+	delivery := storage.DeliveryImmediate
+	switch {
+	case urgent:
+	case user.HasDigest():
+		delivery = storage.DeliveryDigest
+	case user.InQuietHours(time.Now()):
+		delivery = storage.DeliveryDeferred
+	}
+
+	// Create alert history record, which also queues the message for retries if sending fails
+	history, err := n.storage.CreateAlertHistory(ctx, alert.ID, alert.MarketID, previousPrice, currentPrice, changePct, delivery, message)
 	if err != nil {
 		n.log.Errorf("Failed to create alert history: %v", err)
 		return err
 	}
 
-	// Format and send notification
-	message := telegram.FormatAlertNotification(
-		marketTitle,
-		alert.MarketID,
-		previousPrice,
-		currentPrice,
-		changePct,
-		alert.ThresholdPct,
-	)
+	if delivery != storage.DeliveryImmediate {
+		n.log.Infof("Held %s alert for user %d on market %s for %s delivery (%.2f%%)", alert.AlertType, user.TelegramID, alert.MarketID, delivery, changePct)
+		return nil
+	}
+
+	if err := n.deliver(ctx, user, alert, history); err != nil {
+		return err
+	}
+
+	n.log.Infof("Sent %s alert to user %d for market %s (%.2f%%)", alert.AlertType, user.TelegramID, alert.MarketID, changePct)
+	return nil
+}
+
+// deliver sends a queued notification and marks it as sent. On failure the notification
+// is rescheduled with backoff, or dropped along with the user's alerts if the bot was blocked.
+// Sends cut short by the context are left for the outbox to retry
+func (n *Notifier) deliver(ctx context.Context, user *storage.User, alert *storage.Alert, history *storage.AlertHistory) error {
+	sendErr := n.bot.SendAlertNotification(ctx, user.TelegramID, history.Message, telegram.BuildNotificationMenu(alert))
+	if sendErr == nil {
+		if err := n.storage.MarkMessageSent(ctx, history.ID); err != nil {
+			n.log.Warnf("Failed to mark message as sent for history %d: %v", history.ID, err)
+		}
+		return nil
+	}
 
-	err = n.bot.SendAlertNotification(user.TelegramID, message)
+	// The cycle ran out of time while the notification was still queued. Its lease is still
+	// held, so the outbox sends it once the lease expires
+	if ctx.Err() != nil {
+		n.log.Warnf("Notification %d to user %d left to the outbox: %v", history.ID, user.TelegramID, sendErr)
+		return sendErr
+	}
+
+	if telegram.IsBlockedError(sendErr) {
+		n.log.Warnf("User %d blocked the bot, pausing their alerts", user.TelegramID)
+		if _, err := n.storage.PauseUserAlerts(ctx, user.ID); err != nil {
+			n.log.Errorf("Failed to pause alerts of user %d: %v", user.TelegramID, err)
+		}
+		if err := n.storage.ExpireNotification(ctx, history.ID, sendErr.Error()); err != nil {
+			n.log.Errorf("Failed to expire notification %d: %v", history.ID, err)
+		}
+		return sendErr
+	}
+
+	nextAttempt := time.Now().Add(retryBackoff(history.Attempts))
+	if retryAfter, ok := telegram.RetryAfter(sendErr); ok {
+		nextAttempt = time.Now().Add(retryAfter)
+	}
+	if err := n.storage.RecordDeliveryFailure(ctx, history.ID, nextAttempt, sendErr.Error()); err != nil {
+		n.log.Errorf("Failed to record delivery failure for history %d: %v", history.ID, err)
+	}
+
+	n.log.Errorf("Failed to send notification to user %d, retrying at %s: %v", user.TelegramID, nextAttempt.Format(time.TimeOnly), sendErr)
+	return sendErr
+}
+
+// SendMarketStatusChange tells the users with active alerts on a market that its status changed,
+// and archives all of the market's alerts once it has resolved
+func (n *Notifier) SendMarketStatusChange(ctx context.Context, previous, market *storage.Market) error {
+	alerts, err := n.storage.GetUnarchivedAlertsByMarket(ctx, market.MarketID)
+	if err != nil {
+		return err
+	}
+
+	resolved := market.IsResolved()
+	if resolved {
+		if _, err := n.storage.ArchiveMarketAlerts(ctx, market.MarketID); err != nil {
+			return err
+		}
+	}
+
+	// Count each user's alerts on the market; users with only paused alerts aren't notified
+	var userIDs []int64
+	alertCounts := make(map[int64]int)
+	notify := make(map[int64]bool)
+	for _, alert := range alerts {
+		if _, seen := alertCounts[alert.UserID]; !seen {
+			userIDs = append(userIDs, alert.UserID)
+		}
+		alertCounts[alert.UserID]++
+		if alert.IsActive {
+			notify[alert.UserID] = true
+		}
+	}
+
+	for _, userID := range userIDs {
+		if !notify[userID] {
+			continue
+		}
+
+		user, err := n.storage.GetUserByID(ctx, userID)
+		if err != nil {
+			n.log.Errorf("Failed to get user %d: %v", userID, err)
+			continue
+		}
+
+		archived := 0
+		if resolved {
+			archived = alertCounts[userID]
+		}
+		message := telegram.FormatMarketStatusNotification(previous, market, archived)
+		if err := n.bot.SendAlertNotification(ctx, user.TelegramID, message, nil); err != nil {
+			n.log.Errorf("Failed to send market status change to user %d: %v", user.TelegramID, err)
+		}
+	}
+
+	n.log.Infof("Market %s changed status from %s to %s (resolved: %v)", market.MarketID, previous.StatusName(), market.StatusName(), resolved)
+	return nil
+}
+
+// DeliverDeferredAlerts sends one summary per user of the alerts held back during quiet hours
+// once their quiet hours have ended
+func (n *Notifier) DeliverDeferredAlerts(ctx context.Context) {
+	users, err := n.storage.GetUsersWithDeferredAlerts(ctx)
+	if err != nil {
+		n.log.Errorf("Failed to get users with deferred alerts: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		if user.InQuietHours(now) {
+			continue
+		}
+
+		if err := n.sendDeferredSummary(ctx, &user); err != nil {
+			n.log.Errorf("Failed to send deferred alerts to user %d: %v", user.TelegramID, err)
+		}
+	}
+}
+
+// sendDeferredSummary sends the held back alerts of a user as one message and marks them as sent
+func (n *Notifier) sendDeferredSummary(ctx context.Context, user *storage.User) error {
+	history, err := n.storage.GetDeferredAlertHistory(ctx, user.ID, storage.DeliveryDeferred)
 	if err != nil {
-		n.log.Errorf("Failed to send notification to user %d: %v", user.TelegramID, err)
+		return err
+	}
+	if len(history) == 0 {
+		return nil
+	}
+
+	alerts, err := n.storage.GetAlertsByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	alertsByID := make(map[int64]*storage.Alert, len(alerts))
+	for i := range alerts {
+		alertsByID[alerts[i].ID] = &alerts[i]
+	}
+
+	entries := make([]telegram.HistoryEntry, 0, len(history))
+	for _, h := range history {
+		entry := telegram.HistoryEntry{
+			TriggeredAt:   h.TriggeredAt,
+			PreviousPrice: h.PreviousPrice,
+			CurrentPrice:  h.CurrentPrice,
+			ChangePct:     h.ChangePct,
+		}
+		if alert, ok := alertsByID[h.AlertID]; ok {
+			entry.Label = telegram.AlertLabel(alert)
+		}
+		entries = append(entries, entry)
+	}
+
+	message := telegram.FormatDeferredSummary(entries, user.Location())
+	if err := n.bot.SendAlertNotification(ctx, user.TelegramID, message, nil); err != nil {
+		// Nobody is left to read the summary if the bot was blocked
+		if telegram.IsBlockedError(err) {
+			if _, pauseErr := n.storage.PauseUserAlerts(ctx, user.ID); pauseErr != nil {
+				n.log.Errorf("Failed to pause alerts of user %d: %v", user.TelegramID, pauseErr)
+			}
+			for _, h := range history {
+				if expireErr := n.storage.ExpireNotification(ctx, h.ID, err.Error()); expireErr != nil {
+					n.log.Errorf("Failed to expire notification %d: %v", h.ID, expireErr)
+				}
+			}
+		}
 		return err
 	}
 
-	// Mark message as sent
-	if err := n.storage.MarkMessageSent(ctx, history.ID); err != nil {
-		n.log.Warnf("Failed to mark message as sent for history %d: %v", history.ID, err)
+	for _, h := range history {
+		if err := n.storage.MarkMessageSent(ctx, h.ID); err != nil {
+			n.log.Warnf("Failed to mark message as sent for history %d: %v", h.ID, err)
+		}
 	}
 
-	n.log.Infof("Sent price alert to user %d for market %s (%.2f%%)", user.TelegramID, alert.MarketID, changePct)
+	n.log.Infof("Sent %d deferred alerts to user %d", len(history), user.TelegramID)
 	return nil
 }
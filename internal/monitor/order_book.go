@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// fetchBook fetches and stores the top of a token's order book and returns it with the snapshot stored
+// before it. Returns nil books if the order book is unavailable, which only skips the order book checks
+func (pc *PriceChecker) fetchBook(ctx context.Context, marketID, tokenID string) (previous, current *storage.BookSnapshot) {
+	orderBook, err := pc.apiClient.GetOrderBook(ctx, tokenID)
+	if err != nil {
+		pc.log.Warnf("Failed to get order book for %s: %v", tokenID, err)
+		return nil, nil
+	}
+
+	summary, err := orderBook.Summarize()
+	if err != nil {
+		pc.log.Warnf("Failed to parse order book for %s: %v", tokenID, err)
+		return nil, nil
+	}
+
+	previous, err = pc.storage.GetLatestBookSnapshot(ctx, tokenID)
+	if err != nil && err != sql.ErrNoRows {
+		pc.log.Errorf("Failed to get last book snapshot: %v", err)
+		return nil, nil
+	}
+
+	current = &storage.BookSnapshot{
+		TokenID:  tokenID,
+		MarketID: marketID,
+		BestBid:  summary.BestBid,
+		BestAsk:  summary.BestAsk,
+		BidSize:  summary.BidSize,
+		AskSize:  summary.AskSize,
+		BidDepth: summary.BidDepth,
+		AskDepth: summary.AskDepth,
+	}
+	if err := pc.storage.StoreBookSnapshot(ctx, current); err != nil {
+		pc.log.Errorf("Failed to store book snapshot: %v", err)
+	}
+
+	pc.log.Debugf("Market %s (token %s): bid=%.4f x %.2f, ask=%.4f x %.2f, spread=%.1f¢",
+		marketID, tokenID, current.BestBid, current.BidSize, current.BestAsk, current.AskSize, current.SpreadCents())
+
+	return previous, current
+}
+
+// checkBook triggers a spread or liquidity alert when the order book moved into the alert's condition
+// since the previous snapshot, so a book that stays wide or thin is only reported once
+func (pc *PriceChecker) checkBook(ctx context.Context, alert *storage.Alert, marketTitle string, previous, current *storage.BookSnapshot) {
+	if previous == nil || current == nil {
+		return
+	}
+
+	if !bookConditionMet(alert, current) || bookConditionMet(alert, previous) {
+		return
+	}
+
+	allowed, err := pc.allowTrigger(ctx, alert, current.TokenID, 0)
+	if err != nil {
+		pc.log.Errorf("Failed to check cooldown for alert %d: %v", alert.ID, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	pc.log.Infof("Order book alert triggered for market %s (token %s): spread %.1f¢ (was %.1f¢), top liquidity %.2f (was %.2f)",
+		alert.MarketID, current.TokenID, current.SpreadCents(), previous.SpreadCents(), current.TopLiquidity(), previous.TopLiquidity())
+
+	// Send notification
+	if err := pc.notifier.SendBookAlert(ctx, alert, marketTitle, previous, current); err != nil {
+		pc.log.Errorf("Failed to send order book alert: %v", err)
+	}
+}
+
+// bookConditionMet reports whether an order book meets a spread or liquidity alert's condition.
+// A book missing a side counts as too wide, and as too thin on that side
+func bookConditionMet(alert *storage.Alert, book *storage.BookSnapshot) bool {
+	switch alert.AlertType {
+	case storage.AlertTypeSpread:
+		return !book.HasBothSides() || book.SpreadCents() >= alert.SpreadCents
+	case storage.AlertTypeLiquidity:
+		return book.TopLiquidity() < alert.MinLiquidity
+	default:
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+func TestBookConditionMet(t *testing.T) {
+	spread := &storage.Alert{AlertType: storage.AlertTypeSpread, SpreadCents: 3}
+	liquidity := &storage.Alert{AlertType: storage.AlertTypeLiquidity, MinLiquidity: 100}
+
+	tests := []struct {
+		name  string
+		alert *storage.Alert
+		book  storage.BookSnapshot
+		want  bool
+	}{
+		{
+			name:  "spread below the limit",
+			alert: spread,
+			book:  storage.BookSnapshot{BestBid: 0.50, BestAsk: 0.52, BidSize: 1000, AskSize: 1000},
+			want:  false,
+		},
+		{
+			name:  "spread exactly at the limit",
+			alert: spread,
+			book:  storage.BookSnapshot{BestBid: 0.47, BestAsk: 0.50, BidSize: 1000, AskSize: 1000},
+			want:  true,
+		},
+		{
+			name:  "spread above the limit",
+			alert: spread,
+			book:  storage.BookSnapshot{BestBid: 0.40, BestAsk: 0.50, BidSize: 1000, AskSize: 1000},
+			want:  true,
+		},
+		{
+			name:  "spread with no asks",
+			alert: spread,
+			book:  storage.BookSnapshot{BestBid: 0.50, BidSize: 1000},
+			want:  true,
+		},
+		{
+			name:  "spread with an empty book",
+			alert: spread,
+			book:  storage.BookSnapshot{},
+			want:  true,
+		},
+		{
+			name:  "liquidity above the minimum on both sides",
+			alert: liquidity,
+			book:  storage.BookSnapshot{BestBid: 0.50, BestAsk: 0.52, BidSize: 400, AskSize: 400},
+			want:  false,
+		},
+		{
+			name:  "liquidity thin on one side",
+			alert: liquidity,
+			book:  storage.BookSnapshot{BestBid: 0.50, BestAsk: 0.52, BidSize: 400, AskSize: 50},
+			want:  true,
+		},
+		{
+			name:  "liquidity exactly at the minimum",
+			alert: liquidity,
+			book:  storage.BookSnapshot{BestBid: 0.50, BestAsk: 0.50, BidSize: 200, AskSize: 200},
+			want:  false,
+		},
+		{
+			name:  "liquidity with no bids",
+			alert: liquidity,
+			book:  storage.BookSnapshot{BestAsk: 0.52, AskSize: 400},
+			want:  true,
+		},
+		{
+			name:  "other alert types never match",
+			alert: &storage.Alert{AlertType: storage.AlertTypeSpike},
+			book:  storage.BookSnapshot{},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bookConditionMet(tt.alert, &tt.book); got != tt.want {
+				t.Errorf("bookConditionMet() = %v, want %v (spread %.2f¢, top liquidity %.2f)",
+					got, tt.want, tt.book.SpreadCents(), tt.book.TopLiquidity())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// outboxInterval is how often unsent notifications are checked for retries
+	outboxInterval = 15 * time.Second
+	// outboxBatchSize is the most notifications retried per check
+	outboxBatchSize = 50
+	// notificationMaxAge is how long a notification is retried before it is dropped as stale
+	notificationMaxAge = time.Hour
+
+	retryBaseDelay = 15 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+)
+
+// Outbox retries alert notifications that failed to send
+type Outbox struct {
+	storage  *storage.Storage
+	notifier *Notifier
+	log      *logrus.Logger
+}
+
+// NewOutbox creates a new outbox worker
+func NewOutbox(storage *storage.Storage, notifier *Notifier, log *logrus.Logger) *Outbox {
+	return &Outbox{
+		storage:  storage,
+		notifier: notifier,
+		log:      log,
+	}
+}
+
+// Start retries due notifications until the context is cancelled
+func (o *Outbox) Start(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.retryPending(ctx)
+		}
+	}
+}
+
+// retryPending drops stale notifications and resends the ones whose backoff has elapsed
+func (o *Outbox) retryPending(ctx context.Context) {
+	expired, err := o.storage.ExpireStaleNotifications(ctx, time.Now().Add(-notificationMaxAge))
+	if err != nil {
+		o.log.Errorf("Failed to expire stale notifications: %v", err)
+	} else if expired > 0 {
+		o.log.Warnf("Dropped %d notifications that could not be sent within %v", expired, notificationMaxAge)
+	}
+
+	// Claimed notifications are leased, so the next check doesn't resend them while they wait in the send queue
+	pending, err := o.storage.ClaimPendingNotifications(ctx, outboxBatchSize)
+	if err != nil {
+		o.log.Errorf("Failed to claim pending notifications: %v", err)
+		return
+	}
+
+	for i := range pending {
+		history := &pending[i]
+
+		alert, err := o.storage.GetAlert(ctx, history.AlertID)
+		if err != nil {
+			o.log.Errorf("Failed to get alert %d for notification %d: %v", history.AlertID, history.ID, err)
+			continue
+		}
+
+		user, err := o.storage.GetUserByID(ctx, alert.UserID)
+		if err != nil {
+			o.log.Errorf("Failed to get user %d for notification %d: %v", alert.UserID, history.ID, err)
+			continue
+		}
+
+		if err := o.notifier.deliver(ctx, user, alert, history); err != nil {
+			continue
+		}
+
+		o.log.Infof("Sent notification %d to user %d after %d failed attempts", history.ID, user.TelegramID, history.Attempts)
+	}
+}
+
+// retryBackoff returns the delay before the next send after the given number of failed attempts
+func retryBackoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 0; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
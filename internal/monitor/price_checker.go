@@ -3,54 +3,164 @@ package monitor
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"math"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/qmitry/opinion-alert-bot/internal/api"
 	"github.com/qmitry/opinion-alert-bot/internal/storage"
+	"github.com/qmitry/opinion-alert-bot/internal/telegram"
 	"github.com/sirupsen/logrus"
 )
 
+// hysteresisRatio is the share of the last reported move the price has to give back
+// before a move in the same direction is reported again
+const hysteresisRatio = 0.5
+
 // PriceChecker handles price spike detection logic
 type PriceChecker struct {
-	apiClient *api.Client
-	storage   *storage.Storage
-	notifier  *Notifier
-	log       *logrus.Logger
+	apiClient       *api.Client
+	markets         *MarketCache
+	storage         *storage.Storage
+	notifier        *Notifier
+	defaultCooldown time.Duration
+	log             *logrus.Logger
+
+	// Spikes waiting to persist for their alert's confirmation polls, by alert ID
+	pendingMu    sync.Mutex
+	pendingMoves map[int64]*pendingMove
+
+	// Volatility estimates of tokens watched by adaptive spike alerts, by token ID
+	volatilityMu sync.Mutex
+	volatilities map[string]*volatilityEstimate
 }
 
 // NewPriceChecker creates a new price checker instance
-func NewPriceChecker(apiClient *api.Client, storage *storage.Storage, notifier *Notifier, log *logrus.Logger) *PriceChecker {
+func NewPriceChecker(apiClient *api.Client, storage *storage.Storage, notifier *Notifier, defaultCooldown time.Duration, log *logrus.Logger) *PriceChecker {
 	return &PriceChecker{
-		apiClient: apiClient,
-		storage:   storage,
-		notifier:  notifier,
-		log:       log,
+		apiClient:       apiClient,
+		markets:         NewMarketCache(apiClient, storage, notifier, log),
+		storage:         storage,
+		notifier:        notifier,
+		defaultCooldown: defaultCooldown,
+		log:             log,
+		pendingMoves:    make(map[int64]*pendingMove),
+		volatilities:    make(map[string]*volatilityEstimate),
 	}
 }
 
 // CheckMarketPrice checks a market for price spikes and triggers alerts
 func (pc *PriceChecker) CheckMarketPrice(ctx context.Context, marketID string, alerts []storage.Alert) error {
-	// Get market details for market name
-	marketDetails, err := pc.apiClient.GetMarketDetails(ctx, marketID)
+	// Get market name and default token from the cache, so only prices are fetched on every poll
+	market, err := pc.markets.Get(ctx, marketID)
 	if err != nil {
 		pc.log.Warnf("Failed to get market details for %s: %v", marketID, err)
 		return err
 	}
 
-	// Determine which token to track
-	// For alerts with token_id set, use that; otherwise fall back to YesTokenID
-	var tokenID string
-	if len(alerts) > 0 && alerts[0].TokenID != nil && *alerts[0].TokenID != "" {
-		tokenID = *alerts[0].TokenID
-	} else {
-		tokenID = marketDetails.YesTokenID
+	// Prices of resolved markets no longer move; their alerts are archived
+	if market.IsResolved() {
+		pc.log.Debugf("Skipping resolved market %s", marketID)
+		return nil
+	}
+
+	// Group alerts by the token they watch so every outcome is priced independently
+	// Alerts without token_id fall back to the market's default token. Consistency alerts compare
+	// all outcomes of the market and are checked by the ConsistencyChecker, so they need no price here
+	alertsByToken := make(map[string][]storage.Alert)
+	var tokenIDs []string
+	for _, alert := range alerts {
+		if alert.MarketID != marketID || !alert.IsActive || alert.AlertType == storage.AlertTypeConsistency {
+			continue
+		}
+
+		tokenID := market.DefaultTokenID
+		if alert.TokenID != nil && *alert.TokenID != "" {
+			tokenID = *alert.TokenID
+		}
+
+		if tokenID == "" {
+			pc.log.Warnf("No token ID available for alert %d on market %s (may be multi-outcome market without token_id set)", alert.ID, marketID)
+			continue
+		}
+
+		if _, exists := alertsByToken[tokenID]; !exists {
+			tokenIDs = append(tokenIDs, tokenID)
+		}
+		alertsByToken[tokenID] = append(alertsByToken[tokenID], alert)
+	}
+
+	// Volumes come with the market details, so they are only fetched for markets with volume alerts
+	var volumes map[string]*tokenVolume
+	for _, alert := range alerts {
+		if alert.AlertType == storage.AlertTypeVolume && alert.VolumeMultiple > 0 {
+			volumes = pc.fetchVolumes(ctx, market)
+			break
+		}
 	}
 
-	if tokenID == "" {
-		pc.log.Warnf("No token ID available for market %s (may be multi-outcome market without token_id set)", marketID)
+	// Check each token, continuing past failures so one bad outcome doesn't block the others
+	var firstErr error
+	for _, tokenID := range tokenIDs {
+		if err := pc.checkTokenPrice(ctx, marketID, market.Title, tokenID, alertsByToken[tokenID], volumes[tokenID]); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// tokenVolume is the traded volume of the market a token belongs to
+type tokenVolume struct {
+	total float64 // Cumulative volume since the market opened
+	day   float64 // Volume over the trailing 24 hours
+}
+
+// fetchVolumes fetches the current traded volumes of a market by token. Outcomes of a multi-outcome
+// market have their own volumes; both tokens of a binary market share the market's volume.
+// Returns nil if the volumes are unavailable, which only skips the volume surge checks
+func (pc *PriceChecker) fetchVolumes(ctx context.Context, market *storage.Market) map[string]*tokenVolume {
+	details, err := pc.apiClient.GetMarketDetailsOfType(ctx, market.MarketID, market.IsCategorical())
+	if err != nil {
+		pc.log.Warnf("Failed to get volumes of market %s: %v", market.MarketID, err)
 		return nil
 	}
 
+	volumes := make(map[string]*tokenVolume)
+	add := func(detail *api.MarketDetail) {
+		total, err := strconv.ParseFloat(detail.Volume, 64)
+		if err != nil {
+			return
+		}
+		day, err := strconv.ParseFloat(detail.Volume24h, 64)
+		if err != nil {
+			return
+		}
+		volume := &tokenVolume{total: total, day: day}
+		for _, tokenID := range []string{detail.YesTokenID, detail.NoTokenID} {
+			if tokenID != "" {
+				volumes[tokenID] = volume
+			}
+		}
+	}
+
+	if details.IsCategorical() {
+		for i := range details.ChildMarkets {
+			add(&details.ChildMarkets[i])
+		}
+	} else {
+		add(details)
+	}
+	return volumes
+}
+
+// checkTokenPrice fetches and stores the price of a single token and triggers the alerts watching it.
+// volume is the token's current traded volume, nil if it wasn't fetched
+func (pc *PriceChecker) checkTokenPrice(ctx context.Context, marketID, marketTitle, tokenID string, alerts []storage.Alert, volume *tokenVolume) error {
 	// Get current token price
 	tokenPrice, err := pc.apiClient.GetTokenPrice(ctx, tokenID)
 	if err != nil {
@@ -72,48 +182,371 @@ func (pc *PriceChecker) CheckMarketPrice(ctx context.Context, marketID string, a
 		size = 0
 	}
 
+	// The latest price is that of the last trade
+	trade := &storage.TokenPrice{
+		TokenID:  tokenID,
+		MarketID: marketID,
+		Price:    currentPrice,
+		Side:     tokenPrice.Side,
+		Size:     size,
+		TradedAt: unixTime(tokenPrice.Timestamp),
+	}
+
+	// Remember the last stored price before storing the current one (used by crossing and volume alerts)
+	lastTokenPrice, err := pc.storage.GetLatestPrice(ctx, tokenID)
+	if err != nil && err != sql.ErrNoRows {
+		pc.log.Errorf("Failed to get last stored price: %v", err)
+		return err
+	}
+
+	// Adaptive alerts measure the move against the token's volatility before the current price
+	var volatility *volatilityEstimate
+	for _, alert := range alerts {
+		if alert.AlertType == storage.AlertTypeSpike && alert.ThresholdUnit == storage.ThresholdUnitSigma {
+			volatility = pc.volatility(ctx, tokenID)
+			break
+		}
+	}
+
 	// Store current price
-	if err := pc.storage.StoreTokenPrice(ctx, tokenID, marketID, currentPrice, tokenPrice.Side, size); err != nil {
+	if err := pc.storage.StoreTokenPrice(ctx, tokenID, marketID, currentPrice, trade.Side, size, trade.TradedAt); err != nil {
 		pc.log.Errorf("Failed to store token price: %v", err)
 		return err
 	}
 
-	// Get price from 1 minute ago
-	previousTokenPrice, err := pc.storage.GetPriceOneMinuteAgo(ctx, marketID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			pc.log.Debugf("No historical price available for market %s yet", marketID)
-			return nil
+	// Store the volume snapshot that later polls measure the volume traded since against
+	if volume != nil {
+		if err := pc.storage.StoreVolumeSnapshot(ctx, tokenID, marketID, volume.total, volume.day); err != nil {
+			pc.log.Errorf("Failed to store volume snapshot: %v", err)
+			return err
+		}
+	}
+
+	// The order book is only fetched for tokens with spread or liquidity alerts
+	var previousBook, book *storage.BookSnapshot
+	for _, alert := range alerts {
+		if alert.AlertType == storage.AlertTypeSpread || alert.AlertType == storage.AlertTypeLiquidity {
+			previousBook, book = pc.fetchBook(ctx, marketID, tokenID)
+			break
 		}
-		pc.log.Errorf("Failed to get previous price: %v", err)
-		return err
+	}
+
+	// Reference prices and volumes are looked up once per distinct lookback window
+	referencePrices := make(map[time.Duration]*storage.TokenPrice)
+	referenceVolumes := make(map[time.Duration]*storage.VolumeSnapshot)
+
+	// Check each alert watching this token, skipping alerts snoozed from a notification
+	now := time.Now()
+	for _, alert := range alerts {
+		if alert.IsMuted(now) {
+			continue
+		}
+
+		switch alert.AlertType {
+		case storage.AlertTypeCrossing:
+			pc.checkCrossing(ctx, &alert, marketTitle, tokenID, lastTokenPrice, currentPrice)
+		case storage.AlertTypeSpread, storage.AlertTypeLiquidity:
+			pc.checkBook(ctx, &alert, marketTitle, previousBook, book)
+		case storage.AlertTypeVolume:
+			pc.checkVolume(ctx, &alert, marketTitle, volume, referenceVolumes, trade, lastTokenPrice)
+		default:
+			pc.checkSpike(ctx, &alert, marketTitle, trade, referencePrices, volatility)
+		}
+	}
+
+	pc.updateVolatility(trade, now)
+
+	return nil
+}
+
+// checkSpike triggers a spike alert when the price moved by at least the threshold over the alert's window
+// and the move passes the alert's noise filters. volatility is the token's volatility estimate, nil if not available
+func (pc *PriceChecker) checkSpike(ctx context.Context, alert *storage.Alert, marketTitle string, trade *storage.TokenPrice, referencePrices map[time.Duration]*storage.TokenPrice, volatility *volatilityEstimate) {
+	tokenID, currentPrice := trade.TokenID, trade.Price
+	window := alert.Window()
+
+	previousTokenPrice, fetched := referencePrices[window]
+	if !fetched {
+		var err error
+		previousTokenPrice, err = pc.storage.GetPriceWindowAgo(ctx, tokenID, window)
+		if err != nil && err != sql.ErrNoRows {
+			pc.log.Errorf("Failed to get previous price: %v", err)
+			return
+		}
+		referencePrices[window] = previousTokenPrice
+	}
+
+	if previousTokenPrice == nil {
+		pc.log.Debugf("No price from %v ago available for token %s (market %s) yet", window, tokenID, alert.MarketID)
+		return
+	}
+
+	// Adaptive alerts wait until enough price history is known to tell a usual move from an unusual one
+	sigma := 0.0
+	if alert.ThresholdUnit == storage.ThresholdUnitSigma {
+		if volatility == nil {
+			pc.log.Debugf("No volatility estimate for token %s (market %s) yet", tokenID, alert.MarketID)
+			return
+		}
+		sigma = volatility.over(window)
 	}
 
 	previousPrice := previousTokenPrice.Price
 
 	// Calculate percentage change
 	changePct := ((currentPrice - previousPrice) / previousPrice) * 100
+	change := thresholdChange(alert, previousPrice, currentPrice, sigma)
 
-	pc.log.Debugf("Market %s (token %s): current=%.4f, previous=%.4f, change=%.2f%%",
-		marketID, tokenID, currentPrice, previousPrice, changePct)
+	pc.log.Debugf("Market %s (token %s, window %v): current=%.4f, previous=%.4f, change=%.2f%% (%.2f %s)",
+		alert.MarketID, tokenID, window, currentPrice, previousPrice, changePct, change, alert.ThresholdUnit)
 
-	// Check each alert for this market
-	for _, alert := range alerts {
-		if alert.MarketID != marketID || !alert.IsActive {
-			continue
+	// Check if change exceeds threshold in the alert's unit and direction
+	if !exceedsThreshold(alert, change) {
+		pc.endMove(ctx, alert)
+		return
+	}
+
+	allowed, err := pc.allowTrigger(ctx, alert, tokenID, changePct)
+	if err != nil {
+		pc.log.Errorf("Failed to check cooldown for alert %d: %v", alert.ID, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	// A move set by a trade too small to trust doesn't count toward confirming it
+	if tradeTooSmall(alert, trade) {
+		pc.suppress(ctx, alert, previousPrice, currentPrice, changePct,
+			fmt.Sprintf("last trade of %g shares, below the %g share minimum", trade.Size, alert.MinTradeSize))
+		return
+	}
+
+	if !pc.confirmMove(alert, previousPrice, currentPrice, changePct) {
+		pc.log.Debugf("Alert %d waiting for the move to persist for %d polls", alert.ID, alert.ConfirmPolls)
+		return
+	}
+
+	pc.log.Infof("Alert triggered for market %s (token %s): %.2f%% change over %v (threshold: %.1f %s)",
+		alert.MarketID, tokenID, changePct, window, alert.ThresholdValue(), alert.ThresholdUnit)
+
+	// Adaptive alerts report how unusual the move was
+	zScore := 0.0
+	if alert.ThresholdUnit == storage.ThresholdUnitSigma {
+		zScore = change
+	}
+
+	// Send notification
+	if err := pc.notifier.SendPriceAlert(ctx, alert, marketTitle, previousPrice, currentPrice, changePct, zScore); err != nil {
+		pc.log.Errorf("Failed to send price alert: %v", err)
+	}
+
+}
+
+// thresholdChange returns a price change in the alert's threshold unit. Percent mode measures the relative
+// change, cents mode the absolute change in price points, and sigma mode the absolute change in standard
+// deviations sigma of the token's price changes over the alert's window
+func thresholdChange(alert *storage.Alert, previousPrice, currentPrice, sigma float64) float64 {
+	switch alert.ThresholdUnit {
+	case storage.ThresholdUnitCents:
+		return (currentPrice - previousPrice) * 100
+	case storage.ThresholdUnitSigma:
+		return (currentPrice - previousPrice) / sigma
+	default:
+		return ((currentPrice - previousPrice) / previousPrice) * 100
+	}
+}
+
+// exceedsThreshold reports whether a change in the alert's threshold unit reaches its threshold in its direction
+func exceedsThreshold(alert *storage.Alert, change float64) bool {
+	threshold := alert.ThresholdValue()
+	switch alert.Direction {
+	case storage.DirectionUp:
+		return change >= threshold
+	case storage.DirectionDown:
+		return -change >= threshold
+	default:
+		return math.Abs(change) >= threshold
+	}
+}
+
+// checkCrossing triggers a crossing alert when the price moved through the alert's level
+// between the previous and the current stored price
+func (pc *PriceChecker) checkCrossing(ctx context.Context, alert *storage.Alert, marketTitle, tokenID string, lastTokenPrice *storage.TokenPrice, currentPrice float64) {
+	if alert.LevelPrice == nil || lastTokenPrice == nil {
+		return
+	}
+
+	level := *alert.LevelPrice
+	previousPrice := lastTokenPrice.Price
+
+	var crossed bool
+	switch alert.LevelDirection {
+	case storage.CrossAbove:
+		crossed = previousPrice < level && currentPrice >= level
+	case storage.CrossBelow:
+		crossed = previousPrice > level && currentPrice <= level
+	}
+
+	if !crossed {
+		return
+	}
+
+	changePct := ((currentPrice - previousPrice) / previousPrice) * 100
+
+	allowed, err := pc.allowTrigger(ctx, alert, tokenID, changePct)
+	if err != nil {
+		pc.log.Errorf("Failed to check cooldown for alert %d: %v", alert.ID, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	pc.log.Infof("Crossing alert triggered for market %s (token %s): %.4f -> %.4f crossed %s %.4f",
+		alert.MarketID, tokenID, previousPrice, currentPrice, alert.LevelDirection, level)
+
+	// Send notification
+	if err := pc.notifier.SendPriceAlert(ctx, alert, marketTitle, previousPrice, currentPrice, changePct, 0); err != nil {
+		pc.log.Errorf("Failed to send price alert: %v", err)
+	}
+}
+
+// checkVolume triggers a volume alert when the volume traded over the alert's window reached the alert's
+// multiple of its trailing 24h average, or when the latest trade alone is worth at least the alert's trade value
+func (pc *PriceChecker) checkVolume(ctx context.Context, alert *storage.Alert, marketTitle string, volume *tokenVolume, referenceVolumes map[time.Duration]*storage.VolumeSnapshot, trade, lastTokenPrice *storage.TokenPrice) {
+	window := alert.Window()
+	trigger := telegram.VolumeTrigger{Window: window}
+
+	if alert.VolumeMultiple > 0 && volume != nil {
+		previous, fetched := referenceVolumes[window]
+		if !fetched {
+			var err error
+			previous, err = pc.storage.GetVolumeWindowAgo(ctx, trade.TokenID, window)
+			if err != nil && err != sql.ErrNoRows {
+				pc.log.Errorf("Failed to get previous volume: %v", err)
+				return
+			}
+			referenceVolumes[window] = previous
 		}
 
-		// Check if change exceeds threshold (in either direction)
-		if math.Abs(changePct) >= alert.ThresholdPct {
-			pc.log.Infof("Alert triggered for market %s: %.2f%% change (threshold: %.1f%%)",
-				marketID, changePct, alert.ThresholdPct)
+		// The baseline is the 24h volume as of the start of the window, spread evenly over 24 hours
+		if previous != nil {
+			windowVolume := volume.total - previous.Volume
+			average := previous.Volume24h * window.Seconds() / (24 * time.Hour).Seconds()
+
+			pc.log.Debugf("Market %s (token %s, window %v): volume=%.2f, average=%.2f",
+				alert.MarketID, trade.TokenID, window, windowVolume, average)
 
-			// Send notification
-			if err := pc.notifier.SendPriceAlert(ctx, &alert, marketDetails.MarketTitle, previousPrice, currentPrice, changePct); err != nil {
-				pc.log.Errorf("Failed to send price alert: %v", err)
+			if average > 0 && windowVolume >= average*alert.VolumeMultiple {
+				trigger.WindowVolume = windowVolume
+				trigger.AverageVolume = average
 			}
+		} else {
+			pc.log.Debugf("No volume from %v ago available for token %s (market %s) yet", window, trade.TokenID, alert.MarketID)
 		}
 	}
 
-	return nil
+	// The latest-price endpoint keeps returning the last trade until a new one happens,
+	// so a trade is only considered on the first poll that sees it, and only if it is recent
+	recent := trade.TradedAt == nil || time.Since(*trade.TradedAt) <= window
+	if alert.PrintNotional > 0 && recent && !sameTrade(lastTokenPrice, trade) && trade.Size*trade.Price >= alert.PrintNotional {
+		trigger.TradeSide = trade.Side
+		trigger.TradeSize = trade.Size
+		trigger.TradePrice = trade.Price
+	}
+
+	if trigger.WindowVolume == 0 && trigger.TradeSize == 0 {
+		return
+	}
+
+	allowed, err := pc.allowTrigger(ctx, alert, trade.TokenID, 0)
+	if err != nil {
+		pc.log.Errorf("Failed to check cooldown for alert %d: %v", alert.ID, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	pc.log.Infof("Volume alert triggered for market %s (token %s): window volume %.2f (average %.2f), trade %.2f @ %.4f",
+		alert.MarketID, trade.TokenID, trigger.WindowVolume, trigger.AverageVolume, trigger.TradeSize, trigger.TradePrice)
+
+	previousPrice := trade.Price
+	if lastTokenPrice != nil {
+		previousPrice = lastTokenPrice.Price
+	}
+
+	// Send notification
+	if err := pc.notifier.SendVolumeAlert(ctx, alert, marketTitle, trigger, previousPrice, trade.Price); err != nil {
+		pc.log.Errorf("Failed to send volume alert: %v", err)
+	}
+
+}
+
+// sameTrade reports whether the latest price comes from the same trade as the last stored one.
+// Trades are told apart by their time, or by price, side and size if the API didn't report it
+func sameTrade(last, trade *storage.TokenPrice) bool {
+	if last == nil {
+		return false
+	}
+	if last.TradedAt != nil && trade.TradedAt != nil {
+		return last.TradedAt.Equal(*trade.TradedAt)
+	}
+	return last.Price == trade.Price && last.Side == trade.Side && last.Size == trade.Size
+}
+
+// allowTrigger applies the alert's cooldown and hysteresis rules. alert_history is the
+// source of truth, so both rules hold across restarts.
+func (pc *PriceChecker) allowTrigger(ctx context.Context, alert *storage.Alert, tokenID string, changePct float64) (bool, error) {
+	last, err := pc.storage.GetLastAlertHistory(ctx, alert.ID, false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return true, nil
+		}
+		return false, err
+	}
+
+	// Cooldown: never notify the same alert twice within the cooldown period
+	cooldown := alert.Cooldown(pc.defaultCooldown)
+	if elapsed := time.Since(last.TriggeredAt); elapsed < cooldown {
+		pc.log.Debugf("Alert %d in cooldown (%v of %v elapsed)", alert.ID, elapsed.Round(time.Second), cooldown)
+		return false, nil
+	}
+
+	// Crossings and order book alerts are edge-triggered and volume alerts aren't about price moves,
+	// so only the cooldown applies to anything but spikes
+	if alert.AlertType != storage.AlertTypeSpike {
+		return true, nil
+	}
+
+	// A move in the opposite direction is always a new move
+	if (changePct > 0) != (last.ChangePct > 0) {
+		return true, nil
+	}
+
+	// Hysteresis: the same move is not reported again until the price has given back
+	// part of the last reported move at some point since it was reported
+	low, high, err := pc.storage.GetPriceRange(ctx, tokenID, last.TriggeredAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Price history since the last trigger has been cleaned up, so the move is long settled
+			return true, nil
+		}
+		return false, err
+	}
+
+	move := last.CurrentPrice - last.PreviousPrice
+	settleLevel := last.CurrentPrice - move*hysteresisRatio
+
+	settled := low <= settleLevel
+	if move < 0 {
+		settled = high >= settleLevel
+	}
+
+	if !settled {
+		pc.log.Debugf("Alert %d suppressed by hysteresis: price has not settled back to %.4f since last trigger", alert.ID, settleLevel)
+	}
+
+	return settled, nil
 }
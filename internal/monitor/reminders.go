@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/api"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+	"github.com/qmitry/opinion-alert-bot/internal/telegram"
+	"github.com/sirupsen/logrus"
+)
+
+// reminderCheckInterval is how often the reminder scheduler looks for markets nearing their cutoff
+const reminderCheckInterval = time.Minute
+
+// ReminderScheduler reminds users that a tracked market is about to stop trading. Sent reminders
+// are recorded in the database, so none are repeated or lost across restarts
+type ReminderScheduler struct {
+	storage   *storage.Storage
+	apiClient *api.Client
+	bot       *telegram.Bot
+	log       *logrus.Logger
+}
+
+// NewReminderScheduler creates a new cutoff reminder scheduler
+func NewReminderScheduler(storage *storage.Storage, apiClient *api.Client, bot *telegram.Bot, log *logrus.Logger) *ReminderScheduler {
+	return &ReminderScheduler{
+		storage:   storage,
+		apiClient: apiClient,
+		bot:       bot,
+		log:       log,
+	}
+}
+
+// Start runs the reminder scheduler until the context is cancelled
+func (r *ReminderScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sendDueReminders(ctx)
+		}
+	}
+}
+
+// sendDueReminders sends the reminders that are due for every opted-in user
+func (r *ReminderScheduler) sendDueReminders(ctx context.Context) {
+	users, err := r.storage.GetReminderUsers(ctx)
+	if err != nil {
+		r.log.Errorf("Failed to get reminder users: %v", err)
+		return
+	}
+
+	// 24h volumes fetched this round, shared by users tracking the same market
+	volumes := make(map[string]string)
+
+	now := time.Now()
+	for _, user := range users {
+		if err := r.sendUserReminders(ctx, &user, now, volumes); err != nil {
+			r.log.Errorf("Failed to send cutoff reminders to user %d: %v", user.TelegramID, err)
+		}
+	}
+}
+
+// sendUserReminders sends one reminder per tracked market with a reminder offset reached.
+// After downtime, overdue offsets are covered by a single reminder
+func (r *ReminderScheduler) sendUserReminders(ctx context.Context, user *storage.User, now time.Time, volumes map[string]string) error {
+	offsets := user.ReminderOffsets()
+
+	alerts, err := r.storage.GetAlertsByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	markets, err := r.storage.GetMarketsByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// Only markets with active alerts are reminded of
+	alertsByMarket := make(map[string][]storage.Alert)
+	for _, alert := range alerts {
+		if alert.IsActive {
+			alertsByMarket[alert.MarketID] = append(alertsByMarket[alert.MarketID], alert)
+		}
+	}
+
+	for marketID, marketAlerts := range alertsByMarket {
+		market, ok := markets[marketID]
+		if !ok || market.CutoffAt == nil || market.IsResolved() {
+			continue
+		}
+
+		remaining := market.CutoffAt.Sub(now)
+		if remaining <= 0 {
+			continue
+		}
+
+		sent, err := r.storage.GetSentCutoffReminders(ctx, user.ID, marketID, *market.CutoffAt)
+		if err != nil {
+			return err
+		}
+
+		var due []time.Duration
+		for _, offset := range offsets {
+			if remaining <= offset && !containsDuration(sent, offset) {
+				due = append(due, offset)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		if _, ok := volumes[marketID]; !ok {
+			volumes[marketID] = r.volume24h(ctx, market)
+		}
+
+		message := telegram.FormatCutoffReminder(market, remaining, r.currentPrices(ctx, market, marketAlerts), volumes[marketID], user.Location())
+		if err := r.bot.SendAlertNotification(ctx, user.TelegramID, message, nil); err != nil {
+			return err
+		}
+
+		if err := r.storage.RecordCutoffReminders(ctx, user.ID, marketID, *market.CutoffAt, due); err != nil {
+			return err
+		}
+
+		r.log.Infof("Sent cutoff reminder to user %d for market %s (%v before cutoff)", user.TelegramID, marketID, remaining.Round(time.Minute))
+	}
+
+	return nil
+}
+
+// currentPrices returns the latest recorded price of every outcome the alerts watch
+func (r *ReminderScheduler) currentPrices(ctx context.Context, market *storage.Market, alerts []storage.Alert) []telegram.ReminderPrice {
+	var prices []telegram.ReminderPrice
+	seen := make(map[string]bool)
+	for _, alert := range alerts {
+		tokenID := market.DefaultTokenID
+		if alert.TokenID != nil && *alert.TokenID != "" {
+			tokenID = *alert.TokenID
+		}
+		if tokenID == "" || seen[tokenID] {
+			continue
+		}
+		seen[tokenID] = true
+
+		latest, err := r.storage.GetLatestPrice(ctx, tokenID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				r.log.Warnf("Failed to get latest price of token %s: %v", tokenID, err)
+			}
+			continue
+		}
+
+		label := alert.OutcomeName
+		if label == "" {
+			label = market.YesLabel
+		}
+		if label == "" {
+			label = "Yes"
+		}
+		prices = append(prices, telegram.ReminderPrice{Label: label, Price: latest.Price})
+	}
+	return prices
+}
+
+// volume24h fetches the market's current 24h volume, "" if unavailable
+func (r *ReminderScheduler) volume24h(ctx context.Context, market *storage.Market) string {
+	details, err := r.apiClient.GetMarketDetailsOfType(ctx, market.MarketID, market.IsCategorical())
+	if err != nil {
+		r.log.Warnf("Failed to get 24h volume of market %s: %v", market.MarketID, err)
+		return ""
+	}
+
+	volume, err := strconv.ParseFloat(details.Volume24h, 64)
+	if err != nil {
+		return ""
+	}
+	return telegram.FormatVolume(volume)
+}
+
+// containsDuration reports whether a slice contains the given duration
+func containsDuration(durations []time.Duration, d time.Duration) bool {
+	for _, v := range durations {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
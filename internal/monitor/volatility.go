@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// volatilityHalfLife is the age at which a price change counts half as much toward a token's volatility
+const volatilityHalfLife = 6 * time.Hour
+
+// volatilityLookback is how much stored price history a token's volatility estimate is seeded from
+const volatilityLookback = 24 * time.Hour
+
+// minVolatilitySamples is the number of price changes an estimate needs before adaptive alerts use it
+const minVolatilitySamples = 30
+
+// minVolatility is the lowest standard deviation over a window in price points, so a market
+// that barely moved for hours doesn't fire on its first one-tick move
+const minVolatility = 0.005
+
+// volatilityEstimate is an exponentially weighted estimate of how much a token's price moves
+type volatilityEstimate struct {
+	variance  float64 // Weighted mean of squared price changes per second
+	samples   int
+	lastPrice float64
+	lastAt    time.Time
+}
+
+// add folds the price change since the last observed price into the estimate. Changes are
+// normalized by the time between the two prices, so irregular polling doesn't skew the estimate
+func (e *volatilityEstimate) add(price float64, at time.Time) {
+	if e.lastAt.IsZero() {
+		e.lastPrice, e.lastAt = price, at
+		return
+	}
+
+	elapsed := at.Sub(e.lastAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	change := price - e.lastPrice
+	rate := change * change / elapsed
+	if e.samples == 0 {
+		e.variance = rate
+	} else {
+		weight := 1 - math.Exp(-elapsed*math.Ln2/volatilityHalfLife.Seconds())
+		e.variance += weight * (rate - e.variance)
+	}
+
+	e.samples++
+	e.lastPrice, e.lastAt = price, at
+}
+
+// over returns the standard deviation of the token's price change over the given window in price points
+func (e *volatilityEstimate) over(window time.Duration) float64 {
+	return math.Max(math.Sqrt(e.variance*window.Seconds()), minVolatility)
+}
+
+// volatility returns the token's volatility estimate before the current price is added, seeding it
+// from the stored price history when none is kept yet or the kept one went stale.
+// Returns nil while the estimate has too few price changes to be trusted
+func (pc *PriceChecker) volatility(ctx context.Context, tokenID string) *volatilityEstimate {
+	pc.volatilityMu.Lock()
+	estimate, ok := pc.volatilities[tokenID]
+	if ok && time.Since(estimate.lastAt) <= volatilityHalfLife {
+		snapshot := *estimate
+		estimate = &snapshot
+	} else {
+		ok = false
+	}
+	pc.volatilityMu.Unlock()
+
+	if !ok {
+		history, err := pc.storage.GetPriceHistory(ctx, tokenID, time.Now().Add(-volatilityLookback))
+		if err != nil {
+			pc.log.Errorf("Failed to load price history for volatility of token %s: %v", tokenID, err)
+			return nil
+		}
+
+		estimate = &volatilityEstimate{}
+		for _, price := range history {
+			estimate.add(price.Price, price.RecordedAt)
+		}
+
+		snapshot := *estimate
+		pc.volatilityMu.Lock()
+		pc.volatilities[tokenID] = &snapshot
+		pc.volatilityMu.Unlock()
+	}
+
+	if estimate.samples < minVolatilitySamples {
+		return nil
+	}
+	return estimate
+}
+
+// updateVolatility adds a stored price to the token's volatility estimate, if one is kept
+func (pc *PriceChecker) updateVolatility(price *storage.TokenPrice, at time.Time) {
+	pc.volatilityMu.Lock()
+	defer pc.volatilityMu.Unlock()
+
+	if estimate, ok := pc.volatilities[price.TokenID]; ok {
+		estimate.add(price.Price, at)
+	}
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVolatilityEstimateAdd(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	type observation struct {
+		price float64
+		after time.Duration // Since start
+	}
+
+	tests := []struct {
+		name         string
+		observations []observation
+		wantVariance float64
+		wantSamples  int
+	}{
+		{
+			name:         "first price only sets the reference",
+			observations: []observation{{0.50, 0}},
+			wantVariance: 0,
+			wantSamples:  0,
+		},
+		{
+			name:         "first change seeds the variance",
+			observations: []observation{{0.50, 0}, {0.60, 100 * time.Second}},
+			wantVariance: 0.01 / 100,
+			wantSamples:  1,
+		},
+		{
+			name:         "changes are normalized by the time between prices",
+			observations: []observation{{0.50, 0}, {0.60, 400 * time.Second}},
+			wantVariance: 0.01 / 400,
+			wantSamples:  1,
+		},
+		{
+			name:         "prices at the same time are ignored",
+			observations: []observation{{0.50, 0}, {0.60, 100 * time.Second}, {0.90, 100 * time.Second}},
+			wantVariance: 0.01 / 100,
+			wantSamples:  1,
+		},
+		{
+			name:         "a change one half-life later counts half",
+			observations: []observation{{0.50, 0}, {0.60, 100 * time.Second}, {0.60, 100*time.Second + volatilityHalfLife}},
+			wantVariance: 0.01 / 100 / 2,
+			wantSamples:  2,
+		},
+		{
+			name:         "an unchanged price decays the variance",
+			observations: []observation{{0.50, 0}, {0.60, 100 * time.Second}, {0.60, 100*time.Second + 2*volatilityHalfLife}},
+			wantVariance: 0.01 / 100 / 4,
+			wantSamples:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var estimate volatilityEstimate
+			for _, o := range tt.observations {
+				estimate.add(o.price, start.Add(o.after))
+			}
+
+			if estimate.samples != tt.wantSamples {
+				t.Errorf("samples = %d, want %d", estimate.samples, tt.wantSamples)
+			}
+			if math.Abs(estimate.variance-tt.wantVariance) > 1e-12 {
+				t.Errorf("variance = %g, want %g", estimate.variance, tt.wantVariance)
+			}
+		})
+	}
+}
+
+func TestVolatilityEstimateOver(t *testing.T) {
+	tests := []struct {
+		name     string
+		variance float64
+		window   time.Duration
+		want     float64
+	}{
+		{
+			name:     "scales with the square root of the window",
+			variance: 0.0001,
+			window:   100 * time.Second,
+			want:     0.1,
+		},
+		{
+			name:     "four times the window doubles it",
+			variance: 0.0001,
+			window:   400 * time.Second,
+			want:     0.2,
+		},
+		{
+			name:     "floored for a market that barely moves",
+			variance: 1e-12,
+			window:   time.Minute,
+			want:     minVolatility,
+		},
+		{
+			name:     "floored without any change",
+			variance: 0,
+			window:   time.Hour,
+			want:     minVolatility,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate := volatilityEstimate{variance: tt.variance}
+			if got := estimate.over(tt.window); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("over(%v) = %g, want %g", tt.window, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,41 +2,72 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 )
 
-// CreateAlertHistory creates a new alert history record
-func (s *Storage) CreateAlertHistory(ctx context.Context, alertID int64, marketID string, previousPrice, currentPrice, changePct float64) (*AlertHistory, error) {
+// alertHistoryColumns is the column list shared by every query that returns full alert history rows
+const alertHistoryColumns = `id, alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent, delivery, message, attempts, next_attempt_at, last_error, suppressed_by`
+
+// prefixedAlertHistoryColumns is alertHistoryColumns qualified with the "h" alias for joined queries
+const prefixedAlertHistoryColumns = `h.id, h.alert_id, h.market_id, h.triggered_at, h.previous_price, h.current_price, h.change_pct, h.message_sent, h.delivery, h.message, h.attempts, h.next_attempt_at, h.last_error, h.suppressed_by`
+
+// scanAlertHistory scans a row selected with alertHistoryColumns into an AlertHistory
+func scanAlertHistory(row rowScanner, h *AlertHistory) error {
+	var message, lastError, suppressedBy sql.NullString
+	err := row.Scan(&h.ID, &h.AlertID, &h.MarketID, &h.TriggeredAt, &h.PreviousPrice, &h.CurrentPrice, &h.ChangePct, &h.MessageSent, &h.Delivery, &message, &h.Attempts, &h.NextAttemptAt, &lastError, &suppressedBy)
+	h.Message = message.String
+	h.LastError = lastError.String
+	h.SuppressedBy = suppressedBy.String
+	return err
+}
+
+// NotificationLease is how long a notification being sent is held back from the outbox. Records are
+// created and claimed for retries with this lease, so the outbox doesn't resend a message still in the send queue
+const NotificationLease = 2 * time.Minute
+
+// CreateAlertHistory creates a new alert history record with the given delivery mode and the rendered
+// notification message. Immediate notifications are sent right away by the caller, so the record is leased to it
+func (s *Storage) CreateAlertHistory(ctx context.Context, alertID int64, marketID string, previousPrice, currentPrice, changePct float64, delivery, message string) (*AlertHistory, error) {
 	query := `
-		INSERT INTO alert_history (alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent
-	`
+		INSERT INTO alert_history (alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent, delivery, message, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + alertHistoryColumns
 
+	now := time.Now()
 	history := &AlertHistory{}
-	err := s.db.QueryRowContext(
+	err := scanAlertHistory(s.db.QueryRowContext(
 		ctx, query,
-		alertID, marketID, time.Now(), previousPrice, currentPrice, changePct, false,
-	).Scan(
-		&history.ID,
-		&history.AlertID,
-		&history.MarketID,
-		&history.TriggeredAt,
-		&history.PreviousPrice,
-		&history.CurrentPrice,
-		&history.ChangePct,
-		&history.MessageSent,
-	)
+		alertID, marketID, now, previousPrice, currentPrice, changePct, false, delivery, message, now.Add(NotificationLease),
+	), history)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create alert history: %w", err)
 	}
 
-	s.log.Debugf("Created alert history: alert_id=%d, market=%s, change=%.2f%%", alertID, marketID, changePct)
+	s.log.Debugf("Created alert history: alert_id=%d, market=%s, change=%.2f%%, delivery=%s", alertID, marketID, changePct, delivery)
 	return history, nil
 }
 
+// CreateSuppressedAlertHistory records a trigger held back by the alert's noise filters, so users can
+// see what their filters dropped. Suppressed records are never sent and don't start a cooldown
+func (s *Storage) CreateSuppressedAlertHistory(ctx context.Context, alertID int64, marketID string, previousPrice, currentPrice, changePct float64, suppressedBy string) error {
+	query := `
+		INSERT INTO alert_history (alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent, delivery, suppressed_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, alertID, marketID, time.Now(), previousPrice, currentPrice, changePct, false, DeliverySuppressed, suppressedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create suppressed alert history: %w", err)
+	}
+
+	s.log.Debugf("Recorded suppressed trigger: alert_id=%d, market=%s, change=%.2f%%, reason=%s", alertID, marketID, changePct, suppressedBy)
+	return nil
+}
+
 // MarkMessageSent marks an alert history record as having its message sent
 func (s *Storage) MarkMessageSent(ctx context.Context, historyID int64) error {
 	query := `UPDATE alert_history SET message_sent = true WHERE id = $1`
@@ -49,17 +80,40 @@ func (s *Storage) MarkMessageSent(ctx context.Context, historyID int64) error {
 	return nil
 }
 
-// GetAlertHistoryByAlertID retrieves all history records for a specific alert
-func (s *Storage) GetAlertHistoryByAlertID(ctx context.Context, alertID int64, limit int) ([]AlertHistory, error) {
+// GetLastAlertHistory retrieves the most recent history record for a specific alert
+// Suppressed triggers are skipped unless suppressed is set, which retrieves the most recent suppressed one
+func (s *Storage) GetLastAlertHistory(ctx context.Context, alertID int64, suppressed bool) (*AlertHistory, error) {
 	query := `
-		SELECT id, alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent
+		SELECT ` + alertHistoryColumns + `
+		FROM alert_history
+		WHERE alert_id = $1 AND (delivery = $2) = $3
+		ORDER BY triggered_at DESC
+		LIMIT 1
+	`
+
+	h := &AlertHistory{}
+	err := scanAlertHistory(s.db.QueryRowContext(ctx, query, alertID, DeliverySuppressed, suppressed), h)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get last alert history: %w", err)
+	}
+
+	return h, nil
+}
+
+// GetAlertHistoryByAlertID retrieves a page of history records for a specific alert
+func (s *Storage) GetAlertHistoryByAlertID(ctx context.Context, alertID int64, limit, offset int) ([]AlertHistory, error) {
+	query := `
+		SELECT ` + alertHistoryColumns + `
 		FROM alert_history
 		WHERE alert_id = $1
 		ORDER BY triggered_at DESC
-		LIMIT $2
+		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, alertID, limit)
+	rows, err := s.db.QueryContext(ctx, query, alertID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get alert history: %w", err)
 	}
@@ -68,7 +122,7 @@ func (s *Storage) GetAlertHistoryByAlertID(ctx context.Context, alertID int64, l
 	var history []AlertHistory
 	for rows.Next() {
 		var h AlertHistory
-		if err := rows.Scan(&h.ID, &h.AlertID, &h.MarketID, &h.TriggeredAt, &h.PreviousPrice, &h.CurrentPrice, &h.ChangePct, &h.MessageSent); err != nil {
+		if err := scanAlertHistory(rows, &h); err != nil {
 			return nil, fmt.Errorf("failed to scan alert history: %w", err)
 		}
 		history = append(history, h)
@@ -80,7 +134,7 @@ func (s *Storage) GetAlertHistoryByAlertID(ctx context.Context, alertID int64, l
 // GetRecentAlertHistory retrieves recent alert history across all alerts
 func (s *Storage) GetRecentAlertHistory(ctx context.Context, since time.Time, limit int) ([]AlertHistory, error) {
 	query := `
-		SELECT id, alert_id, market_id, triggered_at, previous_price, current_price, change_pct, message_sent
+		SELECT ` + alertHistoryColumns + `
 		FROM alert_history
 		WHERE triggered_at >= $1
 		ORDER BY triggered_at DESC
@@ -96,7 +150,84 @@ func (s *Storage) GetRecentAlertHistory(ctx context.Context, since time.Time, li
 	var history []AlertHistory
 	for rows.Next() {
 		var h AlertHistory
-		if err := rows.Scan(&h.ID, &h.AlertID, &h.MarketID, &h.TriggeredAt, &h.PreviousPrice, &h.CurrentPrice, &h.ChangePct, &h.MessageSent); err != nil {
+		if err := scanAlertHistory(rows, &h); err != nil {
+			return nil, fmt.Errorf("failed to scan alert history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// GetAlertHistoryByUserID retrieves a page of history records across all alerts of a user
+func (s *Storage) GetAlertHistoryByUserID(ctx context.Context, userID int64, limit, offset int) ([]AlertHistory, error) {
+	query := `
+		SELECT ` + prefixedAlertHistoryColumns + `
+		FROM alert_history h
+		JOIN alerts a ON a.id = h.alert_id
+		WHERE a.user_id = $1
+		ORDER BY h.triggered_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user alert history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []AlertHistory
+	for rows.Next() {
+		var h AlertHistory
+		if err := scanAlertHistory(rows, &h); err != nil {
+			return nil, fmt.Errorf("failed to scan alert history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// CountAlertHistory counts the history records of a user's alerts triggered since the given time
+// An alertID of 0 counts across all of the user's alerts. Suppressed triggers are only counted if withSuppressed is set
+func (s *Storage) CountAlertHistory(ctx context.Context, userID, alertID int64, since time.Time, withSuppressed bool) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM alert_history h
+		JOIN alerts a ON a.id = h.alert_id
+		WHERE a.user_id = $1 AND ($2::BIGINT = 0 OR h.alert_id = $2) AND h.triggered_at >= $3
+		  AND ($4 OR h.delivery <> $5)
+	`
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, userID, alertID, since, withSuppressed, DeliverySuppressed).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count alert history: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetDeferredAlertHistory retrieves the unsent alerts of a user held back with the given delivery mode
+// (deferred or digest), oldest first
+func (s *Storage) GetDeferredAlertHistory(ctx context.Context, userID int64, delivery string) ([]AlertHistory, error) {
+	query := `
+		SELECT ` + prefixedAlertHistoryColumns + `
+		FROM alert_history h
+		JOIN alerts a ON a.id = h.alert_id
+		WHERE a.user_id = $1 AND h.delivery = $2 AND h.message_sent = false
+		ORDER BY h.triggered_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, delivery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deferred alert history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []AlertHistory
+	for rows.Next() {
+		var h AlertHistory
+		if err := scanAlertHistory(rows, &h); err != nil {
 			return nil, fmt.Errorf("failed to scan alert history: %w", err)
 		}
 		history = append(history, h)
@@ -104,3 +235,139 @@ func (s *Storage) GetRecentAlertHistory(ctx context.Context, since time.Time, li
 
 	return history, nil
 }
+
+// GetDigestAlertHistory retrieves the unsent digest alerts of a user triggered before the end of a digest period,
+// including those of earlier periods whose digest was held back or missed, oldest first
+func (s *Storage) GetDigestAlertHistory(ctx context.Context, userID int64, until time.Time) ([]AlertHistory, error) {
+	query := `
+		SELECT ` + prefixedAlertHistoryColumns + `
+		FROM alert_history h
+		JOIN alerts a ON a.id = h.alert_id
+		WHERE a.user_id = $1 AND h.delivery = $2 AND h.message_sent = false
+		  AND h.triggered_at < $3
+		ORDER BY h.triggered_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, DeliveryDigest, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest alert history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []AlertHistory
+	for rows.Next() {
+		var h AlertHistory
+		if err := scanAlertHistory(rows, &h); err != nil {
+			return nil, fmt.Errorf("failed to scan alert history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// ReleaseDigestAlerts hands the unsent digest alerts of a user who turned digest mode off over to the
+// deferred summary, so they are still delivered. Returns the number of alerts released
+func (s *Storage) ReleaseDigestAlerts(ctx context.Context, userID int64) (int64, error) {
+	query := `
+		UPDATE alert_history
+		SET delivery = $1
+		WHERE delivery = $2 AND message_sent = false
+		  AND alert_id IN (SELECT id FROM alerts WHERE user_id = $3)
+	`
+
+	result, err := s.db.ExecContext(ctx, query, DeliveryDeferred, DeliveryDigest, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release digest alerts: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// ClaimPendingNotifications retrieves unsent immediate notifications whose next retry is due, oldest first,
+// and leases them to the caller by pushing their next attempt back by NotificationLease
+func (s *Storage) ClaimPendingNotifications(ctx context.Context, limit int) ([]AlertHistory, error) {
+	query := `
+		UPDATE alert_history
+		SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id
+			FROM alert_history
+			WHERE delivery = $2 AND message_sent = false AND message IS NOT NULL AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + alertHistoryColumns
+
+	now := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, now.Add(NotificationLease), DeliveryImmediate, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var history []AlertHistory
+	for rows.Next() {
+		var h AlertHistory
+		if err := scanAlertHistory(rows, &h); err != nil {
+			return nil, fmt.Errorf("failed to scan alert history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	// RETURNING doesn't keep the order of the subquery
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].TriggeredAt.Before(history[j].TriggeredAt)
+	})
+
+	return history, nil
+}
+
+// RecordDeliveryFailure records a failed send attempt and schedules the next retry
+func (s *Storage) RecordDeliveryFailure(ctx context.Context, historyID int64, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE alert_history
+		SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2
+		WHERE id = $3
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, nextAttemptAt, lastError, historyID); err != nil {
+		return fmt.Errorf("failed to record delivery failure: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireNotification drops an unsent notification so it is no longer retried
+func (s *Storage) ExpireNotification(ctx context.Context, historyID int64, lastError string) error {
+	query := `UPDATE alert_history SET delivery = $1, last_error = $2 WHERE id = $3`
+
+	if _, err := s.db.ExecContext(ctx, query, DeliveryExpired, lastError, historyID); err != nil {
+		return fmt.Errorf("failed to expire notification: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireStaleNotifications drops unsent immediate notifications triggered before the given time
+// and returns how many were dropped
+func (s *Storage) ExpireStaleNotifications(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		UPDATE alert_history
+		SET delivery = $1
+		WHERE delivery = $2 AND message_sent = false AND triggered_at < $3
+	`
+
+	result, err := s.db.ExecContext(ctx, query, DeliveryExpired, DeliveryImmediate, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire stale notifications: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -9,83 +9,135 @@ import (
 
 const MaxMarketsPerUser = 10
 
+// alertColumns is the column list shared by every query that returns full alert rows.
+// The market name comes from the markets table, falling back to the name saved with the alert
+const alertColumns = `id, user_id, market_id,
+	COALESCE((SELECT NULLIF(m.title, '') FROM markets m WHERE m.market_id = alerts.market_id), market_name, '') AS market_name, outcome_name, token_id, alert_type, level_price, level_direction, threshold_pct, threshold_unit, threshold_cents, threshold_sigma, direction, window_seconds, volume_multiple, print_notional, spread_cents, min_liquidity, band_cents, min_trade_size, confirm_polls, cooldown_seconds, is_active, muted_until, archived_at, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAlert scans a row selected with alertColumns into an Alert
+func scanAlert(row rowScanner, alert *Alert) error {
+	var outcomeName, levelDirection sql.NullString
+	err := row.Scan(
+		&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &outcomeName, &alert.TokenID, &alert.AlertType, &alert.LevelPrice, &levelDirection, &alert.ThresholdPct, &alert.ThresholdUnit, &alert.ThresholdCents, &alert.ThresholdSigma, &alert.Direction, &alert.WindowSeconds, &alert.VolumeMultiple, &alert.PrintNotional, &alert.SpreadCents, &alert.MinLiquidity, &alert.BandCents, &alert.MinTradeSize, &alert.ConfirmPolls, &alert.CooldownSeconds, &alert.IsActive, &alert.MutedUntil, &alert.ArchivedAt, &alert.CreatedAt, &alert.UpdatedAt,
+	)
+	alert.OutcomeName = outcomeName.String
+	alert.LevelDirection = levelDirection.String
+	return err
+}
+
 // CreateAlert creates a new price alert for a user or updates existing one
-func (s *Storage) CreateAlert(ctx context.Context, userID int64, marketID, marketName string, tokenID *string, thresholdPct float64) (*Alert, error) {
-	// Check if alert already exists for this user and market
-	existingAlert, err := s.GetAlertByUserAndMarket(ctx, userID, marketID)
+// A spike alert is unique per user, market and token, so each outcome of a multi-outcome market can be watched
+// separately; crossing alerts are additionally unique per level and direction.
+// The settings are taken from params; ID, IsActive and timestamps are ignored.
+func (s *Storage) CreateAlert(ctx context.Context, params *Alert) (*Alert, error) {
+	tokenIDStr := "nil"
+	if params.TokenID != nil {
+		tokenIDStr = *params.TokenID
+	}
+
+	alertType := params.AlertType
+	if alertType == "" {
+		alertType = AlertTypeSpike
+	}
+
+	thresholdUnit := params.ThresholdUnit
+	if thresholdUnit == "" {
+		thresholdUnit = ThresholdUnitPercent
+	}
+
+	direction := params.Direction
+	if direction == "" {
+		direction = DirectionBoth
+	}
+
+	windowSeconds := params.WindowSeconds
+	if windowSeconds <= 0 {
+		windowSeconds = DefaultWindowSeconds
+	}
+
+	var levelDirection *string
+	if params.LevelDirection != "" {
+		levelDirection = &params.LevelDirection
+	}
+
+	// Resolved markets no longer trade, so their alerts would never trigger or be archived again
+	market, err := s.GetMarket(ctx, params.MarketID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if market != nil && market.IsResolved() {
+		return nil, fmt.Errorf("market %s has resolved", params.MarketID)
+	}
+
+	// Check if the same alert already exists for this user
+	existingAlert, err := s.findMatchingAlert(ctx, params.UserID, params.MarketID, params.TokenID, alertType, params.LevelPrice, params.LevelDirection)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to check existing alert: %w", err)
 	}
 
+	// A new or resumed alert must fit within the market limit
+	if existingAlert == nil || !existingAlert.IsActive {
+		if err := s.CheckMarketLimit(ctx, params.UserID, params.MarketID); err != nil {
+			return nil, err
+		}
+	}
+
 	// If alert exists, update it
 	if existingAlert != nil {
 		query := `
 			UPDATE alerts
-			SET threshold_pct = $1, market_name = $2, token_id = $3, updated_at = $4, is_active = true
-			WHERE user_id = $5 AND market_id = $6
-			RETURNING id, user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at
-		`
+			SET threshold_pct = $1, threshold_unit = $2, threshold_cents = $3, threshold_sigma = $4, direction = $5, window_seconds = $6, cooldown_seconds = $7,
+			    volume_multiple = $8, print_notional = $9, spread_cents = $10, min_liquidity = $11, band_cents = $12,
+			    market_name = $13, outcome_name = $14, updated_at = $15, is_active = true
+			WHERE id = $16
+			RETURNING ` + alertColumns
 		alert := &Alert{}
-		err = s.db.QueryRowContext(
+		err = scanAlert(s.db.QueryRowContext(
 			ctx, query,
-			thresholdPct, marketName, tokenID, time.Now(), userID, marketID,
-		).Scan(
-			&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &alert.TokenID, &alert.ThresholdPct, &alert.IsActive, &alert.CreatedAt, &alert.UpdatedAt,
-		)
+			params.ThresholdPct, thresholdUnit, params.ThresholdCents, params.ThresholdSigma, direction, windowSeconds, params.CooldownSeconds, params.VolumeMultiple, params.PrintNotional, params.SpreadCents, params.MinLiquidity, params.BandCents, params.MarketName, params.OutcomeName, time.Now(), existingAlert.ID,
+		), alert)
 		if err != nil {
 			return nil, fmt.Errorf("failed to update alert: %w", err)
 		}
-		tokenIDStr := "nil"
-		if tokenID != nil {
-			tokenIDStr = *tokenID
-		}
-		s.log.Infof("Updated alert: user_id=%d, market_id=%s, token_id=%s, threshold=%.1f%%", userID, marketID, tokenIDStr, thresholdPct)
+		s.log.Infof("Updated %s alert: user_id=%d, market_id=%s, token_id=%s, threshold=%.1f %s, window=%ds", alertType, params.UserID, params.MarketID, tokenIDStr, params.ThresholdValue(), thresholdUnit, windowSeconds)
 		return alert, nil
 	}
 
-	// Check if user is already tracking 10 unique markets
-	trackedMarkets, err := s.GetTrackedMarketsByUserID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check tracked markets: %w", err)
-	}
-
-	// If user already has 10 markets, reject
-	if len(trackedMarkets) >= MaxMarketsPerUser {
-		return nil, fmt.Errorf("cannot track more than %d markets", MaxMarketsPerUser)
+	// Alerts reference their market, which the monitor fills in once it first checks it
+	if err := s.ensureMarket(ctx, params.MarketID, params.MarketName); err != nil {
+		return nil, err
 	}
 
 	// Create new alert
 	query := `
-		INSERT INTO alerts (user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at
-	`
+		INSERT INTO alerts (user_id, market_id, market_name, outcome_name, token_id, alert_type, level_price, level_direction, threshold_pct, threshold_unit, threshold_cents, threshold_sigma, direction, window_seconds, volume_multiple, print_notional, spread_cents, min_liquidity, band_cents, cooldown_seconds, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		RETURNING ` + alertColumns
 
 	now := time.Now()
 	alert := &Alert{}
-	err = s.db.QueryRowContext(
+	err = scanAlert(s.db.QueryRowContext(
 		ctx, query,
-		userID, marketID, marketName, tokenID, thresholdPct, true, now, now,
-	).Scan(
-		&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &alert.TokenID, &alert.ThresholdPct, &alert.IsActive, &alert.CreatedAt, &alert.UpdatedAt,
-	)
+		params.UserID, params.MarketID, params.MarketName, params.OutcomeName, params.TokenID, alertType, params.LevelPrice, levelDirection, params.ThresholdPct, thresholdUnit, params.ThresholdCents, params.ThresholdSigma, direction, windowSeconds, params.VolumeMultiple, params.PrintNotional, params.SpreadCents, params.MinLiquidity, params.BandCents, params.CooldownSeconds, true, now, now,
+	), alert)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create alert: %w", err)
 	}
 
-	tokenIDStr := "nil"
-	if tokenID != nil {
-		tokenIDStr = *tokenID
-	}
-	s.log.Infof("Created alert: user_id=%d, market_id=%s, token_id=%s, threshold=%.1f%%", userID, marketID, tokenIDStr, thresholdPct)
+	s.log.Infof("Created %s alert: user_id=%d, market_id=%s, token_id=%s, threshold=%.1f %s, window=%ds", alertType, params.UserID, params.MarketID, tokenIDStr, params.ThresholdValue(), thresholdUnit, windowSeconds)
 	return alert, nil
 }
 
 // GetAlertsByUserID retrieves all alerts for a user
 func (s *Storage) GetAlertsByUserID(ctx context.Context, userID int64) ([]Alert, error) {
 	query := `
-		SELECT id, user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM alerts
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -100,7 +152,7 @@ func (s *Storage) GetAlertsByUserID(ctx context.Context, userID int64) ([]Alert,
 	var alerts []Alert
 	for rows.Next() {
 		var alert Alert
-		if err := rows.Scan(&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &alert.TokenID, &alert.ThresholdPct, &alert.IsActive, &alert.CreatedAt, &alert.UpdatedAt); err != nil {
+		if err := scanAlert(rows, &alert); err != nil {
 			return nil, fmt.Errorf("failed to scan alert: %w", err)
 		}
 		alerts = append(alerts, alert)
@@ -112,7 +164,7 @@ func (s *Storage) GetAlertsByUserID(ctx context.Context, userID int64) ([]Alert,
 // GetActiveAlerts retrieves all active alerts
 func (s *Storage) GetActiveAlerts(ctx context.Context) ([]Alert, error) {
 	query := `
-		SELECT id, user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM alerts
 		WHERE is_active = true
 		ORDER BY market_id
@@ -127,7 +179,7 @@ func (s *Storage) GetActiveAlerts(ctx context.Context) ([]Alert, error) {
 	var alerts []Alert
 	for rows.Next() {
 		var alert Alert
-		if err := rows.Scan(&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &alert.TokenID, &alert.ThresholdPct, &alert.IsActive, &alert.CreatedAt, &alert.UpdatedAt); err != nil {
+		if err := scanAlert(rows, &alert); err != nil {
 			return nil, fmt.Errorf("failed to scan alert: %w", err)
 		}
 		alerts = append(alerts, alert)
@@ -201,11 +253,12 @@ func (s *Storage) GetRandomTrackedMarkets(ctx context.Context, limit int) ([]Mar
 	query := `
 		SELECT market_id, market_name
 		FROM (
-			SELECT DISTINCT ON (market_id)
-				market_id,
-				COALESCE(NULLIF(market_name, ''), 'Market #' || market_id) as market_name
-			FROM alerts
-			WHERE is_active = true
+			SELECT DISTINCT ON (a.market_id)
+				a.market_id,
+				COALESCE(NULLIF(m.title, ''), NULLIF(a.market_name, ''), 'Market #' || a.market_id) as market_name
+			FROM alerts a
+			LEFT JOIN markets m ON m.market_id = a.market_id
+			WHERE a.is_active = true
 		) AS unique_markets
 		ORDER BY RANDOM()
 		LIMIT $1
@@ -254,15 +307,13 @@ func (s *Storage) DeleteAlert(ctx context.Context, alertID, userID int64) error
 // GetAlert retrieves an alert by ID
 func (s *Storage) GetAlert(ctx context.Context, alertID int64) (*Alert, error) {
 	query := `
-		SELECT id, user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM alerts
 		WHERE id = $1
 	`
 
 	alert := &Alert{}
-	err := s.db.QueryRowContext(ctx, query, alertID).Scan(
-		&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &alert.TokenID, &alert.ThresholdPct, &alert.IsActive, &alert.CreatedAt, &alert.UpdatedAt,
-	)
+	err := scanAlert(s.db.QueryRowContext(ctx, query, alertID), alert)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -274,18 +325,38 @@ func (s *Storage) GetAlert(ctx context.Context, alertID int64) (*Alert, error) {
 	return alert, nil
 }
 
-// GetAlertByUserAndMarket retrieves an alert by user ID and market ID
-func (s *Storage) GetAlertByUserAndMarket(ctx context.Context, userID int64, marketID string) (*Alert, error) {
+// CheckMarketLimit returns an error if activating an alert on the given market would take the user
+// over MaxMarketsPerUser. Only active alerts count, so paused alerts don't use up the limit
+func (s *Storage) CheckMarketLimit(ctx context.Context, userID int64, marketID string) error {
+	trackedMarkets, err := s.GetTrackedMarketsByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check tracked markets: %w", err)
+	}
+
+	// If user already has 10 markets, reject unless this is another alert on a tracked market
+	if len(trackedMarkets) >= MaxMarketsPerUser && !containsString(trackedMarkets, marketID) {
+		return fmt.Errorf("cannot track more than %d markets", MaxMarketsPerUser)
+	}
+
+	return nil
+}
+
+// findMatchingAlert retrieves the alert a new alert with the given settings would replace,
+// preferring an active alert over a paused one. Archived alerts are kept as they are
+// A nil token ID matches legacy alerts created before tokens were recorded
+func (s *Storage) findMatchingAlert(ctx context.Context, userID int64, marketID string, tokenID *string, alertType string, levelPrice *float64, levelDirection string) (*Alert, error) {
 	query := `
-		SELECT id, user_id, market_id, market_name, token_id, threshold_pct, is_active, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM alerts
-		WHERE user_id = $1 AND market_id = $2 AND is_active = true
+		WHERE user_id = $1 AND market_id = $2 AND COALESCE(token_id, '') = COALESCE($3, '')
+		  AND alert_type = $4 AND COALESCE(level_price, 0) = COALESCE($5::DECIMAL, 0) AND COALESCE(level_direction, '') = $6
+		  AND archived_at IS NULL
+		ORDER BY is_active DESC, updated_at DESC
+		LIMIT 1
 	`
 
 	alert := &Alert{}
-	err := s.db.QueryRowContext(ctx, query, userID, marketID).Scan(
-		&alert.ID, &alert.UserID, &alert.MarketID, &alert.MarketName, &alert.TokenID, &alert.ThresholdPct, &alert.IsActive, &alert.CreatedAt, &alert.UpdatedAt,
-	)
+	err := scanAlert(s.db.QueryRowContext(ctx, query, userID, marketID, tokenID, alertType, levelPrice, levelDirection), alert)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -308,3 +379,140 @@ func (s *Storage) UpdateAlertStatus(ctx context.Context, alertID int64, isActive
 
 	return nil
 }
+
+// PauseUserAlerts pauses every active alert of a user and returns how many were paused
+func (s *Storage) PauseUserAlerts(ctx context.Context, userID int64) (int64, error) {
+	query := `UPDATE alerts SET is_active = false, updated_at = $1 WHERE user_id = $2 AND is_active = true`
+
+	result, err := s.db.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pause alerts: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	s.log.Infof("Paused %d alerts: user_id=%d", rowsAffected, userID)
+	return rowsAffected, nil
+}
+
+// ArchiveMarketAlerts deactivates every alert on a market that has resolved and returns how many were archived
+func (s *Storage) ArchiveMarketAlerts(ctx context.Context, marketID string) (int64, error) {
+	query := `UPDATE alerts SET is_active = false, archived_at = $1, updated_at = $1 WHERE market_id = $2 AND archived_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, time.Now(), marketID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive alerts: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	s.log.Infof("Archived %d alerts: market_id=%s", rowsAffected, marketID)
+	return rowsAffected, nil
+}
+
+// GetUnarchivedAlertsByMarket retrieves the active and paused alerts on a market
+func (s *Storage) GetUnarchivedAlertsByMarket(ctx context.Context, marketID string) ([]Alert, error) {
+	query := `
+		SELECT ` + alertColumns + `
+		FROM alerts
+		WHERE market_id = $1 AND archived_at IS NULL
+		ORDER BY user_id, created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []Alert
+	for rows.Next() {
+		var alert Alert
+		if err := scanAlert(rows, &alert); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// SetAlertMutedUntil snoozes an alert until the given time, or unmutes it when until is nil
+func (s *Storage) SetAlertMutedUntil(ctx context.Context, alertID, userID int64, until *time.Time) error {
+	query := `UPDATE alerts SET muted_until = $1, updated_at = $2 WHERE id = $3 AND user_id = $4`
+
+	result, err := s.db.ExecContext(ctx, query, until, time.Now(), alertID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update alert mute: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateAlertSettings updates the spike settings of an existing alert in place,
+// keeping its id and alert history
+func (s *Storage) UpdateAlertSettings(ctx context.Context, alert *Alert) error {
+	query := `
+		UPDATE alerts
+		SET threshold_pct = $1, threshold_unit = $2, threshold_cents = $3, threshold_sigma = $4, direction = $5,
+		    window_seconds = $6, cooldown_seconds = $7, min_trade_size = $8, confirm_polls = $9, updated_at = $10
+		WHERE id = $11 AND user_id = $12
+	`
+
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx, query,
+		alert.ThresholdPct,
+		alert.ThresholdUnit,
+		alert.ThresholdCents,
+		alert.ThresholdSigma,
+		alert.Direction,
+		alert.WindowSeconds,
+		alert.CooldownSeconds,
+		alert.MinTradeSize,
+		alert.ConfirmPolls,
+		now,
+		alert.ID,
+		alert.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update alert settings: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	alert.UpdatedAt = now
+	s.log.Infof("Updated alert settings: id=%d, user_id=%d", alert.ID, alert.UserID)
+	return nil
+}
+
+// containsString reports whether a string slice contains the given value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StoreBookSnapshot stores the top of a token's order book as fetched now
+func (s *Storage) StoreBookSnapshot(ctx context.Context, book *BookSnapshot) error {
+	query := `
+		INSERT INTO book_snapshots (token_id, market_id, best_bid, best_ask, bid_size, ask_size, bid_depth, ask_depth, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		book.TokenID,
+		book.MarketID,
+		book.BestBid,
+		book.BestAsk,
+		book.BidSize,
+		book.AskSize,
+		book.BidDepth,
+		book.AskDepth,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store book snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestBookSnapshot retrieves the most recent order book snapshot for a given token
+func (s *Storage) GetLatestBookSnapshot(ctx context.Context, tokenID string) (*BookSnapshot, error) {
+	query := `
+		SELECT id, token_id, market_id, best_bid, best_ask, bid_size, ask_size, bid_depth, ask_depth, recorded_at
+		FROM book_snapshots
+		WHERE token_id = $1
+		ORDER BY recorded_at DESC
+		LIMIT 1
+	`
+
+	book := &BookSnapshot{}
+	err := s.db.QueryRowContext(ctx, query, tokenID).Scan(
+		&book.ID,
+		&book.TokenID,
+		&book.MarketID,
+		&book.BestBid,
+		&book.BestAsk,
+		&book.BidSize,
+		&book.AskSize,
+		&book.BidDepth,
+		&book.AskDepth,
+		&book.RecordedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get latest book snapshot: %w", err)
+	}
+
+	return book, nil
+}
+
+// CleanupOldBooks deletes order book snapshots older than the specified duration
+func (s *Storage) CleanupOldBooks(ctx context.Context, olderThan time.Duration) error {
+	query := `DELETE FROM book_snapshots WHERE recorded_at < NOW() - $1::interval`
+
+	result, err := s.db.ExecContext(ctx, query, olderThan.String())
+	if err != nil {
+		return fmt.Errorf("failed to cleanup old book snapshots: %w", err)
+	}
+
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		s.log.Warnf("Failed to get rows affected during cleanup: %v", err)
+		return nil
+	}
+
+	if rowsDeleted > 0 {
+		s.log.Debugf("Cleaned up %d old book snapshots", rowsDeleted)
+	}
+
+	return nil
+}
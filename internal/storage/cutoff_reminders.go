@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetSentCutoffReminders returns the offsets of the reminders already sent to a user for a market's cutoff
+func (s *Storage) GetSentCutoffReminders(ctx context.Context, userID int64, marketID string, cutoffAt time.Time) ([]time.Duration, error) {
+	query := `
+		SELECT offset_seconds
+		FROM cutoff_reminders
+		WHERE user_id = $1 AND market_id = $2 AND cutoff_at = $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, marketID, cutoffAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sent cutoff reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var offsets []time.Duration
+	for rows.Next() {
+		var seconds int
+		if err := rows.Scan(&seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan cutoff reminder: %w", err)
+		}
+		offsets = append(offsets, time.Duration(seconds)*time.Second)
+	}
+
+	return offsets, nil
+}
+
+// RecordCutoffReminders records reminders sent to a user for a market's cutoff so they aren't sent again
+func (s *Storage) RecordCutoffReminders(ctx context.Context, userID int64, marketID string, cutoffAt time.Time, offsets []time.Duration) error {
+	query := `
+		INSERT INTO cutoff_reminders (user_id, market_id, cutoff_at, offset_seconds, sent_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, market_id, cutoff_at, offset_seconds) DO NOTHING
+	`
+
+	now := time.Now()
+	for _, offset := range offsets {
+		if _, err := s.db.ExecContext(ctx, query, userID, marketID, cutoffAt, int(offset.Seconds()), now); err != nil {
+			return fmt.Errorf("failed to record cutoff reminder: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// marketColumns lists the markets columns in the order scanMarket expects
+const marketColumns = `market_id, title, market_type, status, status_enum, cutoff_at, resolved_at, yes_label, no_label, yes_token_id, no_token_id, quote_token, result_token_id, default_token_id, child_markets, fetched_at, created_at, updated_at`
+
+// scanMarket scans a row selected with marketColumns
+func scanMarket(row rowScanner, m *Market) error {
+	var childMarkets []byte
+	err := row.Scan(
+		&m.MarketID, &m.Title, &m.MarketType, &m.Status, &m.StatusEnum, &m.CutoffAt, &m.ResolvedAt,
+		&m.YesLabel, &m.NoLabel, &m.YesTokenID, &m.NoTokenID, &m.QuoteToken, &m.ResultTokenID, &m.DefaultTokenID,
+		&childMarkets, &m.FetchedAt, &m.CreatedAt, &m.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	m.ChildMarkets = nil
+	if err := json.Unmarshal(childMarkets, &m.ChildMarkets); err != nil {
+		return fmt.Errorf("failed to decode child markets: %w", err)
+	}
+	return nil
+}
+
+// GetMarket retrieves a market by its Opinion market ID
+func (s *Storage) GetMarket(ctx context.Context, marketID string) (*Market, error) {
+	query := `SELECT ` + marketColumns + ` FROM markets WHERE market_id = $1`
+
+	market := &Market{}
+	if err := scanMarket(s.db.QueryRowContext(ctx, query, marketID), market); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get market: %w", err)
+	}
+
+	return market, nil
+}
+
+// GetMarketsByUserID retrieves the markets of all of a user's alerts, keyed by market ID
+func (s *Storage) GetMarketsByUserID(ctx context.Context, userID int64) (map[string]*Market, error) {
+	query := `
+		SELECT ` + marketColumns + `
+		FROM markets
+		WHERE market_id IN (SELECT market_id FROM alerts WHERE user_id = $1)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markets: %w", err)
+	}
+	defer rows.Close()
+
+	markets := make(map[string]*Market)
+	for rows.Next() {
+		market := &Market{}
+		if err := scanMarket(rows, market); err != nil {
+			return nil, fmt.Errorf("failed to scan market: %w", err)
+		}
+		markets[market.MarketID] = market
+	}
+
+	return markets, nil
+}
+
+// UpsertMarket stores freshly fetched market metadata, replacing the previous copy
+func (s *Storage) UpsertMarket(ctx context.Context, market *Market) error {
+	childMarkets, err := json.Marshal(market.ChildMarkets)
+	if err != nil {
+		return fmt.Errorf("failed to encode child markets: %w", err)
+	}
+	if market.ChildMarkets == nil {
+		childMarkets = []byte("[]")
+	}
+
+	query := `
+		INSERT INTO markets (market_id, title, market_type, status, status_enum, cutoff_at, resolved_at, yes_label, no_label,
+		                     yes_token_id, no_token_id, quote_token, result_token_id, default_token_id, child_markets, fetched_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (market_id) DO UPDATE
+		SET title = EXCLUDED.title,
+		    market_type = EXCLUDED.market_type,
+		    status = EXCLUDED.status,
+		    status_enum = EXCLUDED.status_enum,
+		    cutoff_at = EXCLUDED.cutoff_at,
+		    resolved_at = EXCLUDED.resolved_at,
+		    yes_label = EXCLUDED.yes_label,
+		    no_label = EXCLUDED.no_label,
+		    yes_token_id = EXCLUDED.yes_token_id,
+		    no_token_id = EXCLUDED.no_token_id,
+		    quote_token = EXCLUDED.quote_token,
+		    result_token_id = EXCLUDED.result_token_id,
+		    default_token_id = EXCLUDED.default_token_id,
+		    child_markets = EXCLUDED.child_markets,
+		    fetched_at = EXCLUDED.fetched_at,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING ` + marketColumns
+
+	now := time.Now()
+	err = scanMarket(s.db.QueryRowContext(
+		ctx, query,
+		market.MarketID, market.Title, market.MarketType, market.Status, market.StatusEnum, market.CutoffAt, market.ResolvedAt,
+		market.YesLabel, market.NoLabel, market.YesTokenID, market.NoTokenID, market.QuoteToken, market.ResultTokenID,
+		market.DefaultTokenID, childMarkets, now, now,
+	), market)
+	if err != nil {
+		return fmt.Errorf("failed to upsert market: %w", err)
+	}
+
+	return nil
+}
+
+// ensureMarket adds a market known only by ID and name, to be filled in by the next monitoring cycle
+func (s *Storage) ensureMarket(ctx context.Context, marketID, title string) error {
+	query := `
+		INSERT INTO markets (market_id, title)
+		VALUES ($1, $2)
+		ON CONFLICT (market_id) DO NOTHING
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, marketID, title); err != nil {
+		return fmt.Errorf("failed to add market: %w", err)
+	}
+
+	return nil
+}
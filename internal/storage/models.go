@@ -1,48 +1,432 @@
 package storage
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alert types
+const (
+	AlertTypeSpike       = "spike"       // Relative price change over a lookback window
+	AlertTypeCrossing    = "crossing"    // Price crossing an absolute level
+	AlertTypeVolume      = "volume"      // Traded volume surge or a single large trade
+	AlertTypeSpread      = "spread"      // Bid-ask spread widening past a number of cents
+	AlertTypeLiquidity   = "liquidity"   // Top-of-book liquidity falling below a notional amount
+	AlertTypeConsistency = "consistency" // Outcome prices of a market no longer summing to $1
+)
+
+// Crossing directions for level alerts
+const (
+	CrossAbove = "above"
+	CrossBelow = "below"
+)
+
+// Spike directions
+const (
+	DirectionBoth = "both"
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// Spike threshold units
+const (
+	ThresholdUnitPercent = "percent" // Relative change in percent
+	ThresholdUnitCents   = "cents"   // Absolute change in price points (1 cent = $0.01)
+	ThresholdUnitSigma   = "sigma"   // Change in standard deviations of the token's recent price changes
+)
+
+// DefaultWindowSeconds is the lookback window of alerts created before windows were configurable
+const DefaultWindowSeconds = 60
+
+// Alert delivery modes recorded in alert history
+const (
+	DeliveryImmediate  = "immediate"  // Sent as soon as the alert triggered
+	DeliveryDeferred   = "deferred"   // Held during quiet hours and sent in a summary afterwards
+	DeliveryDigest     = "digest"     // Collected for the user's hourly or daily digest
+	DeliveryExpired    = "expired"    // Dropped after failing to send for too long
+	DeliverySuppressed = "suppressed" // Held back by the alert's noise filters, never sent
+)
+
+// Digest modes
+const (
+	DigestOff    = "off"
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+// DailyDigestHour is the hour in the user's timezone daily digests are compiled at
+const DailyDigestHour = 9
 
 // User represents a Telegram user
 type User struct {
-	ID         int64     `db:"id"`
-	TelegramID int64     `db:"telegram_id"`
-	Username   string    `db:"username"`
-	CreatedAt  time.Time `db:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at"`
+	ID                 int64      `db:"id"`
+	TelegramID         int64      `db:"telegram_id"`
+	Username           string     `db:"username"`
+	Timezone           string     `db:"timezone"`             // IANA name, empty uses the server timezone
+	QuietStart         *int       `db:"quiet_start"`          // Nullable, hour quiet hours begin at (0-23)
+	QuietEnd           *int       `db:"quiet_end"`            // Nullable, hour quiet hours end at (0-23)
+	UrgentThresholdPct *float64   `db:"urgent_threshold_pct"` // Nullable, changes at least this large ignore quiet hours and digests
+	DigestMode         string     `db:"digest_mode"`          // off, hourly or daily
+	LastDigestAt       *time.Time `db:"last_digest_at"`       // Nullable, end of the last period a digest was compiled for
+	CutoffReminders    string     `db:"cutoff_reminders"`     // Comma-separated seconds before cutoff to send reminders at
+	CreatedAt          time.Time  `db:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at"`
+}
+
+// HasDigest reports whether the user gets batched digests instead of real-time alerts
+func (u *User) HasDigest() bool {
+	return u.DigestMode == DigestHourly || u.DigestMode == DigestDaily
+}
+
+// Location returns the user's timezone, falling back to the server timezone
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// HasQuietHours reports whether the user has set up quiet hours
+func (u *User) HasQuietHours() bool {
+	return u.QuietStart != nil && u.QuietEnd != nil && *u.QuietStart != *u.QuietEnd
+}
+
+// InQuietHours reports whether the given time falls within the user's quiet hours
+// The window may wrap around midnight (e.g. 22-7)
+func (u *User) InQuietHours(now time.Time) bool {
+	if !u.HasQuietHours() {
+		return false
+	}
+
+	hour := now.In(u.Location()).Hour()
+	start, end := *u.QuietStart, *u.QuietEnd
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// ReminderOffsets returns how long before a market's cutoff the user wants reminders, longest first
+func (u *User) ReminderOffsets() []time.Duration {
+	var offsets []time.Duration
+	for _, field := range strings.Split(u.CutoffReminders, ",") {
+		seconds, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		offsets = append(offsets, time.Duration(seconds)*time.Second)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets
+}
+
+// SetReminderOffsets stores the given cutoff reminder offsets
+func (u *User) SetReminderOffsets(offsets []time.Duration) {
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	fields := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		fields = append(fields, strconv.Itoa(int(offset.Seconds())))
+	}
+	u.CutoffReminders = strings.Join(fields, ",")
+}
+
+// IsUrgent reports whether a price change is large enough to be delivered during quiet hours
+func (u *User) IsUrgent(changePct float64) bool {
+	return u.UrgentThresholdPct != nil && math.Abs(changePct) >= *u.UrgentThresholdPct
 }
 
 // Alert represents a user-configured price alert
 type Alert struct {
-	ID           int64     `db:"id"`
-	UserID       int64     `db:"user_id"`
-	MarketID     string    `db:"market_id"`
-	MarketName   string    `db:"market_name"`
-	TokenID      *string   `db:"token_id"` // Nullable for backward compatibility
-	ThresholdPct float64   `db:"threshold_pct"`
-	IsActive     bool      `db:"is_active"`
-	CreatedAt    time.Time `db:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"`
+	ID              int64      `db:"id"`
+	UserID          int64      `db:"user_id"`
+	MarketID        string     `db:"market_id"`
+	MarketName      string     `db:"market_name"`
+	OutcomeName     string     `db:"outcome_name"` // Outcome title for multi-outcome markets, empty for binary
+	TokenID         *string    `db:"token_id"`     // Nullable for backward compatibility
+	AlertType       string     `db:"alert_type"`
+	LevelPrice      *float64   `db:"level_price"`     // Crossing alerts only
+	LevelDirection  string     `db:"level_direction"` // Crossing alerts only: above or below
+	ThresholdPct    float64    `db:"threshold_pct"`
+	ThresholdUnit   string     `db:"threshold_unit"`   // Spike alerts only: percent, cents or sigma
+	ThresholdCents  float64    `db:"threshold_cents"`  // Spike threshold when the unit is cents
+	ThresholdSigma  float64    `db:"threshold_sigma"`  // Spike threshold when the unit is sigma
+	Direction       string     `db:"direction"`        // Spike alerts only: up, down or both
+	WindowSeconds   int        `db:"window_seconds"`   // Lookback window the price change is measured over
+	VolumeMultiple  float64    `db:"volume_multiple"`  // Volume alerts only: window volume vs. its 24h average, 0 if off
+	PrintNotional   float64    `db:"print_notional"`   // Volume alerts only: value of a single trade to alert on, 0 if off
+	SpreadCents     float64    `db:"spread_cents"`     // Spread alerts only: spread in cents to alert at
+	MinLiquidity    float64    `db:"min_liquidity"`    // Liquidity alerts only: top-of-book notional to alert below
+	BandCents       float64    `db:"band_cents"`       // Consistency alerts only: how far in cents the outcome prices may sum away from $1
+	MinTradeSize    float64    `db:"min_trade_size"`   // Spike alerts only: trades smaller than this many shares are ignored, 0 if off
+	ConfirmPolls    int        `db:"confirm_polls"`    // Spike alerts only: consecutive polls a move must persist for, 1 if off
+	CooldownSeconds *int       `db:"cooldown_seconds"` // Nullable, falls back to the global default
+	IsActive        bool       `db:"is_active"`
+	MutedUntil      *time.Time `db:"muted_until"` // Nullable, snoozed alerts don't notify before this time
+	ArchivedAt      *time.Time `db:"archived_at"` // Nullable, set when the market resolved
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+}
+
+// Window returns the alert's lookback window as a duration
+func (a *Alert) Window() time.Duration {
+	if a.WindowSeconds <= 0 {
+		return DefaultWindowSeconds * time.Second
+	}
+	return time.Duration(a.WindowSeconds) * time.Second
+}
+
+// ThresholdValue returns the spike threshold in the alert's unit
+func (a *Alert) ThresholdValue() float64 {
+	switch a.ThresholdUnit {
+	case ThresholdUnitCents:
+		return a.ThresholdCents
+	case ThresholdUnitSigma:
+		return a.ThresholdSigma
+	default:
+		return a.ThresholdPct
+	}
+}
+
+// HasNoiseFilters reports whether the alert ignores small trades or waits for a move to persist
+func (a *Alert) HasNoiseFilters() bool {
+	return a.MinTradeSize > 0 || a.ConfirmPolls > 1
+}
+
+// IsMuted reports whether the alert is snoozed at the given time
+func (a *Alert) IsMuted(now time.Time) bool {
+	return a.MutedUntil != nil && now.Before(*a.MutedUntil)
+}
+
+// IsArchived reports whether the alert was archived because its market resolved
+func (a *Alert) IsArchived() bool {
+	return a.ArchivedAt != nil
+}
+
+// Cooldown returns the minimum time between two notifications of the alert
+func (a *Alert) Cooldown(defaultCooldown time.Duration) time.Duration {
+	if a.CooldownSeconds == nil {
+		return defaultCooldown
+	}
+	return time.Duration(*a.CooldownSeconds) * time.Second
 }
 
 // TokenPrice represents a YES token price snapshot
 type TokenPrice struct {
+	ID         int64      `db:"id"`
+	TokenID    string     `db:"token_id"`
+	MarketID   string     `db:"market_id"`
+	Price      float64    `db:"price"`
+	Side       string     `db:"side"`
+	Size       float64    `db:"size"`
+	TradedAt   *time.Time `db:"traded_at"` // Nullable, time of the trade the price was taken from
+	RecordedAt time.Time  `db:"recorded_at"`
+}
+
+// VolumeSnapshot represents the traded volume of a token's market as reported at one point in time
+type VolumeSnapshot struct {
 	ID         int64     `db:"id"`
 	TokenID    string    `db:"token_id"`
 	MarketID   string    `db:"market_id"`
-	Price      float64   `db:"price"`
-	Side       string    `db:"side"`
-	Size       float64   `db:"size"`
+	Volume     float64   `db:"volume"`     // Cumulative volume since the market opened
+	Volume24h  float64   `db:"volume_24h"` // Volume over the trailing 24 hours
 	RecordedAt time.Time `db:"recorded_at"`
 }
 
+// BookSnapshot represents the top of a token's order book at one point in time.
+// Prices and sizes are 0 for an empty side of the book
+type BookSnapshot struct {
+	ID         int64     `db:"id"`
+	TokenID    string    `db:"token_id"`
+	MarketID   string    `db:"market_id"`
+	BestBid    float64   `db:"best_bid"`
+	BestAsk    float64   `db:"best_ask"`
+	BidSize    float64   `db:"bid_size"`  // Shares at the best bid
+	AskSize    float64   `db:"ask_size"`  // Shares at the best ask
+	BidDepth   float64   `db:"bid_depth"` // Notional of the bids near the best bid
+	AskDepth   float64   `db:"ask_depth"` // Notional of the asks near the best ask
+	RecordedAt time.Time `db:"recorded_at"`
+}
+
+// HasBothSides reports whether the book has both bids and asks
+func (b *BookSnapshot) HasBothSides() bool {
+	return b.BestBid > 0 && b.BestAsk > 0
+}
+
+// SpreadCents returns the bid-ask spread in cents, 0 if a side of the book is empty
+func (b *BookSnapshot) SpreadCents() float64 {
+	if !b.HasBothSides() {
+		return 0
+	}
+	// Rounded to a hundredth of a cent so float noise doesn't decide whether a limit is reached
+	return math.Round((b.BestAsk-b.BestBid)*1e4) / 100
+}
+
+// Mid returns the midpoint between the best bid and ask, or the price of the only side quoted
+func (b *BookSnapshot) Mid() float64 {
+	if !b.HasBothSides() {
+		return b.BestBid + b.BestAsk
+	}
+	return (b.BestBid + b.BestAsk) / 2
+}
+
+// TopLiquidity returns the notional at the best price of the thinner side of the book
+func (b *BookSnapshot) TopLiquidity() float64 {
+	return math.Min(b.BestBid*b.BidSize, b.BestAsk*b.AskSize)
+}
+
+// PriceOHLC summarizes the recorded prices of a token over a period
+type PriceOHLC struct {
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
 // AlertHistory represents a triggered alert record
 type AlertHistory struct {
-	ID            int64     `db:"id"`
-	AlertID       int64     `db:"alert_id"`
-	MarketID      string    `db:"market_id"`
-	TriggeredAt   time.Time `db:"triggered_at"`
-	PreviousPrice float64   `db:"previous_price"`
-	CurrentPrice  float64   `db:"current_price"`
-	ChangePct     float64   `db:"change_pct"`
-	MessageSent   bool      `db:"message_sent"`
+	ID            int64      `db:"id"`
+	AlertID       int64      `db:"alert_id"`
+	MarketID      string     `db:"market_id"`
+	TriggeredAt   time.Time  `db:"triggered_at"`
+	PreviousPrice float64    `db:"previous_price"`
+	CurrentPrice  float64    `db:"current_price"`
+	ChangePct     float64    `db:"change_pct"`
+	MessageSent   bool       `db:"message_sent"`
+	Delivery      string     `db:"delivery"`
+	Message       string     `db:"message"`         // Rendered notification, kept for retries
+	Attempts      int        `db:"attempts"`        // Failed send attempts so far
+	NextAttemptAt *time.Time `db:"next_attempt_at"` // Nullable, when the next retry is due
+	LastError     string     `db:"last_error"`
+	SuppressedBy  string     `db:"suppressed_by"` // Why the noise filters held the trigger back, suppressed records only
+}
+
+// IsSuppressed reports whether the record is a trigger held back by the alert's noise filters
+func (h *AlertHistory) IsSuppressed() bool {
+	return h.Delivery == DeliverySuppressed
+}
+
+// Market status labels shown next to market names
+const (
+	MarketStatusClosed   = "closed"   // Past its cutoff, awaiting resolution
+	MarketStatusResolved = "resolved" // Outcome decided
+)
+
+// Market represents an Opinion market as last fetched by the monitor
+type Market struct {
+	MarketID       string        `db:"market_id"`
+	Title          string        `db:"title"`
+	MarketType     int           `db:"market_type"` // 0=Binary, 1=Categorical/Multi-outcome
+	Status         int           `db:"status"`
+	StatusEnum     string        `db:"status_enum"`
+	CutoffAt       *time.Time    `db:"cutoff_at"`   // Nullable, when trading closes
+	ResolvedAt     *time.Time    `db:"resolved_at"` // Nullable, when the outcome was decided
+	YesLabel       string        `db:"yes_label"`
+	NoLabel        string        `db:"no_label"`
+	YesTokenID     string        `db:"yes_token_id"`
+	NoTokenID      string        `db:"no_token_id"`
+	QuoteToken     string        `db:"quote_token"`
+	ResultTokenID  string        `db:"result_token_id"`  // Winning token once resolved
+	DefaultTokenID string        `db:"default_token_id"` // Token tracked by alerts without their own token
+	ChildMarkets   []MarketChild `db:"child_markets"`    // Outcomes of a multi-outcome market
+	FetchedAt      *time.Time    `db:"fetched_at"`       // Nullable, never fetched if nil
+	CreatedAt      time.Time     `db:"created_at"`
+	UpdatedAt      time.Time     `db:"updated_at"`
+}
+
+// MarketChild is one outcome of a multi-outcome market
+type MarketChild struct {
+	MarketID      int    `json:"marketId"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	YesTokenID    string `json:"yesTokenId"`
+	NoTokenID     string `json:"noTokenId"`
+	ResultTokenID string `json:"resultTokenId"`
+}
+
+// IsCategorical reports whether the market is a multi-outcome market
+func (m *Market) IsCategorical() bool {
+	return m.MarketType == 1
+}
+
+// IsStale reports whether the metadata was never fetched or is older than the given TTL
+func (m *Market) IsStale(ttl time.Duration, now time.Time) bool {
+	return m.FetchedAt == nil || now.Sub(*m.FetchedAt) > ttl
+}
+
+// IsResolved reports whether the market's outcome has been decided
+func (m *Market) IsResolved() bool {
+	return m.ResolvedAt != nil || m.ResultTokenID != "" || m.WinningOutcome() != ""
+}
+
+// WinningOutcome returns the name of the outcome the market resolved to, "" if not decided yet.
+// For multi-outcome markets this is the outcome whose YES token won
+func (m *Market) WinningOutcome() string {
+	if m.IsCategorical() {
+		for _, child := range m.ChildMarkets {
+			if child.ResultTokenID != "" && child.ResultTokenID == child.YesTokenID {
+				return child.Title
+			}
+		}
+		return ""
+	}
+
+	switch {
+	case m.ResultTokenID == "":
+		return ""
+	case m.ResultTokenID == m.YesTokenID:
+		if m.YesLabel != "" {
+			return m.YesLabel
+		}
+		return "Yes"
+	case m.ResultTokenID == m.NoTokenID:
+		if m.NoLabel != "" {
+			return m.NoLabel
+		}
+		return "No"
+	default:
+		return ""
+	}
+}
+
+// StatusName returns the status shown to users, preferring the API's status name
+func (m *Market) StatusName() string {
+	if m.StatusEnum != "" {
+		return m.StatusEnum
+	}
+	return fmt.Sprintf("status %d", m.Status)
+}
+
+// StatusChanged reports whether the market's status, cutoff state or outcome differs from
+// an earlier copy, comparing the cutoff state of each copy at the time it was fetched
+func (m *Market) StatusChanged(previous *Market, now time.Time) bool {
+	previousLabel := ""
+	if previous.FetchedAt != nil {
+		previousLabel = previous.StatusLabel(*previous.FetchedAt)
+	}
+
+	return m.Status != previous.Status ||
+		m.StatusEnum != previous.StatusEnum ||
+		m.ResultTokenID != previous.ResultTokenID ||
+		m.WinningOutcome() != previous.WinningOutcome() ||
+		m.StatusLabel(now) != previousLabel
+}
+
+// StatusLabel returns MarketStatusResolved or MarketStatusClosed for markets no longer trading, "" otherwise
+func (m *Market) StatusLabel(now time.Time) string {
+	switch {
+	case m.IsResolved():
+		return MarketStatusResolved
+	case m.CutoffAt != nil && now.After(*m.CutoffAt):
+		return MarketStatusClosed
+	default:
+		return ""
+	}
 }
@@ -94,9 +94,6 @@ func (s *Storage) RunMigrations() error {
 		// Update existing NULL market_name values to use market_id as fallback
 		`UPDATE alerts SET market_name = 'Market #' || market_id WHERE market_name IS NULL`,
 
-		// Add unique constraint for one alert per market per user
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_user_market_unique ON alerts(user_id, market_id) WHERE is_active = true`,
-
 		// Token prices table
 		`CREATE TABLE IF NOT EXISTS token_prices (
 			id BIGSERIAL PRIMARY KEY,
@@ -126,6 +123,182 @@ func (s *Storage) RunMigrations() error {
 		`CREATE INDEX IF NOT EXISTS idx_token_prices_market_time ON token_prices(market_id, recorded_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_alert_history_alert_id ON alert_history(alert_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_alert_history_triggered_at ON alert_history(triggered_at DESC)`,
+
+		// Track the chosen outcome of multi-outcome markets
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS outcome_name TEXT`,
+
+		// The original one-alert-per-market index is dropped, so several outcomes of the same market can be watched.
+		// Migrations run on every start, so the outdated index must not be created again before this point
+		`DROP INDEX IF EXISTS idx_alerts_user_market_unique`,
+
+		// Per-alert lookback window for spike detection (defaults to the original 1 minute)
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS window_seconds INTEGER NOT NULL DEFAULT 60`,
+
+		// Per-alert notification cooldown (NULL uses the global default)
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS cooldown_seconds INTEGER`,
+
+		// Alert types: relative spikes and absolute level crossings
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS alert_type VARCHAR(20) NOT NULL DEFAULT 'spike'`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS level_price DECIMAL`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS level_direction VARCHAR(10)`,
+
+		// One alert per user, market, token and type, and one crossing alert per level and direction.
+		// Replaces the per-token index of earlier versions, which is no longer created
+		`DROP INDEX IF EXISTS idx_alerts_user_market_token_unique`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_user_market_token_type_unique ON alerts(user_id, market_id, COALESCE(token_id, ''), alert_type, COALESCE(level_price, 0), COALESCE(level_direction, '')) WHERE is_active = true`,
+
+		// Spike direction: up-only, down-only or both
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS direction VARCHAR(10) NOT NULL DEFAULT 'both'`,
+
+		// Spike thresholds can be measured in percent or in absolute cents
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS threshold_unit VARCHAR(10) NOT NULL DEFAULT 'percent'`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS threshold_cents DECIMAL NOT NULL DEFAULT 0`,
+
+		// Price lookups are keyed by token so each outcome of a market has its own history
+		`CREATE INDEX IF NOT EXISTS idx_token_prices_token_time ON token_prices(token_id, recorded_at DESC)`,
+
+		// Alerts snoozed from a notification stay silent until muted_until
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP`,
+
+		// Per-user timezone, quiet hours and urgent threshold
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_start SMALLINT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_end SMALLINT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS urgent_threshold_pct DECIMAL`,
+
+		// Alerts raised during quiet hours are held and delivered later in a summary
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS delivery VARCHAR(20) NOT NULL DEFAULT 'immediate'`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_history_pending ON alert_history(delivery) WHERE message_sent = false`,
+
+		// Digest mode batches alerts into hourly or daily summaries
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_mode VARCHAR(10) NOT NULL DEFAULT 'off'`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMP`,
+
+		// Unsent notifications are retried from alert_history with exponential backoff
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS message TEXT`,
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP`,
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS last_error TEXT`,
+
+		// Cached market metadata so the monitor doesn't fetch market details every poll
+		`CREATE TABLE IF NOT EXISTS markets (
+			market_id VARCHAR(255) PRIMARY KEY,
+			title TEXT NOT NULL DEFAULT '',
+			market_type SMALLINT NOT NULL DEFAULT 0,
+			default_token_id VARCHAR(255) NOT NULL DEFAULT '',
+			fetched_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+
+		// Full market metadata, kept in sync by the monitor
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS status INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS status_enum VARCHAR(50) NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS cutoff_at TIMESTAMP`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS yes_label TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS no_label TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS yes_token_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS no_token_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS quote_token VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS result_token_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS child_markets JSONB NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE markets ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW()`,
+
+		// Markets known only by ID have never been fetched
+		`ALTER TABLE markets ALTER COLUMN fetched_at DROP NOT NULL`,
+		`ALTER TABLE markets ALTER COLUMN fetched_at DROP DEFAULT`,
+
+		// Add markets referenced before the markets table existed, filled in by the next monitoring cycle
+		`INSERT INTO markets (market_id, title, fetched_at)
+			SELECT DISTINCT ON (market_id) market_id, COALESCE(market_name, ''), NULL
+			FROM alerts
+			ORDER BY market_id, updated_at DESC
+		ON CONFLICT (market_id) DO NOTHING`,
+		`INSERT INTO markets (market_id, fetched_at)
+			SELECT DISTINCT market_id, NULL::TIMESTAMP FROM alert_history
+		ON CONFLICT (market_id) DO NOTHING`,
+
+		// Alerts and history reference their market
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_alerts_market') THEN
+				ALTER TABLE alerts ADD CONSTRAINT fk_alerts_market FOREIGN KEY (market_id) REFERENCES markets(market_id);
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_alert_history_market') THEN
+				ALTER TABLE alert_history ADD CONSTRAINT fk_alert_history_market FOREIGN KEY (market_id) REFERENCES markets(market_id);
+			END IF;
+		END $$`,
+
+		// Alerts on resolved markets are archived instead of being polled forever
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP`,
+
+		// Cutoff reminders: offsets chosen by the user and the reminders already sent
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS cutoff_reminders VARCHAR(100) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS cutoff_reminders (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			market_id VARCHAR(255) NOT NULL REFERENCES markets(market_id),
+			cutoff_at TIMESTAMP NOT NULL,
+			offset_seconds INTEGER NOT NULL,
+			sent_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			UNIQUE (user_id, market_id, cutoff_at, offset_seconds)
+		)`,
+
+		// Volume alerts: surge multiple and large trade value, the trade time of each price
+		// so a trade is only alerted on once, and volume snapshots for the trailing baseline
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS volume_multiple DECIMAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS print_notional DECIMAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE token_prices ADD COLUMN IF NOT EXISTS traded_at TIMESTAMP`,
+		`CREATE TABLE IF NOT EXISTS volume_snapshots (
+			id BIGSERIAL PRIMARY KEY,
+			token_id VARCHAR(255) NOT NULL,
+			market_id VARCHAR(255) NOT NULL,
+			volume DECIMAL NOT NULL,
+			volume_24h DECIMAL NOT NULL,
+			recorded_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_volume_snapshots_token_time ON volume_snapshots(token_id, recorded_at DESC)`,
+
+		// Order book alerts: spread and top-of-book liquidity limits, and the book snapshots they compare against
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS spread_cents DECIMAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS min_liquidity DECIMAL NOT NULL DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS book_snapshots (
+			id BIGSERIAL PRIMARY KEY,
+			token_id VARCHAR(255) NOT NULL,
+			market_id VARCHAR(255) NOT NULL,
+			best_bid DECIMAL NOT NULL,
+			best_ask DECIMAL NOT NULL,
+			bid_size DECIMAL NOT NULL,
+			ask_size DECIMAL NOT NULL,
+			bid_depth DECIMAL NOT NULL,
+			ask_depth DECIMAL NOT NULL,
+			recorded_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_book_snapshots_token_time ON book_snapshots(token_id, recorded_at DESC)`,
+
+		// Noise filters of spike alerts, and why a trigger was suppressed by them
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS min_trade_size DECIMAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS confirm_polls INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS suppressed_by TEXT`,
+
+		// Adaptive spike thresholds in standard deviations of the token's recent price changes
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS threshold_sigma DECIMAL NOT NULL DEFAULT 0`,
+
+		// Consistency alerts: band around $1 the outcome prices of a market may sum to
+		`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS band_cents DECIMAL NOT NULL DEFAULT 0`,
+
+		// Store every timestamp with a time zone. lib/pq reads timestamps without one back as UTC,
+		// which put cooldowns, mutes and digest periods off by the server's UTC offset. Existing values
+		// are taken to be in the database's time zone, and converted columns are skipped on later starts
+		`DO $$
+		DECLARE col RECORD;
+		BEGIN
+			FOR col IN
+				SELECT table_name, column_name FROM information_schema.columns
+				WHERE table_schema = current_schema() AND data_type = 'timestamp without time zone'
+			LOOP
+				EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ', col.table_name, col.column_name);
+			END LOOP;
+		END $$`,
 	}
 
 	for i, migration := range migrations {
@@ -7,14 +7,15 @@ import (
 	"time"
 )
 
-// StoreTokenPrice stores a new token price snapshot
-func (s *Storage) StoreTokenPrice(ctx context.Context, tokenID, marketID string, price float64, side string, size float64) error {
+// StoreTokenPrice stores a new token price snapshot. tradedAt is the time of the trade the price
+// was taken from, nil if the API didn't report it
+func (s *Storage) StoreTokenPrice(ctx context.Context, tokenID, marketID string, price float64, side string, size float64, tradedAt *time.Time) error {
 	query := `
-		INSERT INTO token_prices (token_id, market_id, price, side, size, recorded_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO token_prices (token_id, market_id, price, side, size, traded_at, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
-	_, err := s.db.ExecContext(ctx, query, tokenID, marketID, price, side, size, time.Now())
+	_, err := s.db.ExecContext(ctx, query, tokenID, marketID, price, side, size, tradedAt, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to store token price: %w", err)
 	}
@@ -22,27 +23,30 @@ func (s *Storage) StoreTokenPrice(ctx context.Context, tokenID, marketID string,
 	return nil
 }
 
-// GetPriceOneMinuteAgo retrieves the price from approximately 1 minute ago for a given market
-func (s *Storage) GetPriceOneMinuteAgo(ctx context.Context, marketID string) (*TokenPrice, error) {
-	// Get the price from 1 minute ago (allowing a small window)
+// GetPriceWindowAgo retrieves the price recorded closest to one lookback window ago for a given token.
+// Only prices within a sixth of the window around that point are considered (±10s for 1 minute).
+func (s *Storage) GetPriceWindowAgo(ctx context.Context, tokenID string, window time.Duration) (*TokenPrice, error) {
 	query := `
-		SELECT id, token_id, market_id, price, side, size, recorded_at
+		SELECT id, token_id, market_id, price, side, size, traded_at, recorded_at
 		FROM token_prices
-		WHERE market_id = $1
-		  AND recorded_at >= NOW() - INTERVAL '70 seconds'
-		  AND recorded_at <= NOW() - INTERVAL '50 seconds'
-		ORDER BY recorded_at ASC
+		WHERE token_id = $1
+		  AND recorded_at >= NOW() - ($2::float8 + $3::float8) * INTERVAL '1 second'
+		  AND recorded_at <= NOW() - ($2::float8 - $3::float8) * INTERVAL '1 second'
+		ORDER BY ABS(EXTRACT(EPOCH FROM (recorded_at - (NOW() - $2::float8 * INTERVAL '1 second')))) ASC
 		LIMIT 1
 	`
 
+	tolerance := window / 6
+
 	tokenPrice := &TokenPrice{}
-	err := s.db.QueryRowContext(ctx, query, marketID).Scan(
+	err := s.db.QueryRowContext(ctx, query, tokenID, window.Seconds(), tolerance.Seconds()).Scan(
 		&tokenPrice.ID,
 		&tokenPrice.TokenID,
 		&tokenPrice.MarketID,
 		&tokenPrice.Price,
 		&tokenPrice.Side,
 		&tokenPrice.Size,
+		&tokenPrice.TradedAt,
 		&tokenPrice.RecordedAt,
 	)
 
@@ -50,30 +54,31 @@ func (s *Storage) GetPriceOneMinuteAgo(ctx context.Context, marketID string) (*T
 		if err == sql.ErrNoRows {
 			return nil, sql.ErrNoRows
 		}
-		return nil, fmt.Errorf("failed to get price from 1 minute ago: %w", err)
+		return nil, fmt.Errorf("failed to get price from %v ago: %w", window, err)
 	}
 
 	return tokenPrice, nil
 }
 
-// GetLatestPrice retrieves the most recent price for a given market
-func (s *Storage) GetLatestPrice(ctx context.Context, marketID string) (*TokenPrice, error) {
+// GetLatestPrice retrieves the most recent price for a given token
+func (s *Storage) GetLatestPrice(ctx context.Context, tokenID string) (*TokenPrice, error) {
 	query := `
-		SELECT id, token_id, market_id, price, side, size, recorded_at
+		SELECT id, token_id, market_id, price, side, size, traded_at, recorded_at
 		FROM token_prices
-		WHERE market_id = $1
+		WHERE token_id = $1
 		ORDER BY recorded_at DESC
 		LIMIT 1
 	`
 
 	tokenPrice := &TokenPrice{}
-	err := s.db.QueryRowContext(ctx, query, marketID).Scan(
+	err := s.db.QueryRowContext(ctx, query, tokenID).Scan(
 		&tokenPrice.ID,
 		&tokenPrice.TokenID,
 		&tokenPrice.MarketID,
 		&tokenPrice.Price,
 		&tokenPrice.Side,
 		&tokenPrice.Size,
+		&tokenPrice.TradedAt,
 		&tokenPrice.RecordedAt,
 	)
 
@@ -87,6 +92,50 @@ func (s *Storage) GetLatestPrice(ctx context.Context, marketID string) (*TokenPr
 	return tokenPrice, nil
 }
 
+// GetPriceRange retrieves the lowest and highest recorded price for a token since the given time
+func (s *Storage) GetPriceRange(ctx context.Context, tokenID string, since time.Time) (low, high float64, err error) {
+	query := `
+		SELECT MIN(price), MAX(price)
+		FROM token_prices
+		WHERE token_id = $1 AND recorded_at >= $2
+	`
+
+	var minPrice, maxPrice sql.NullFloat64
+	if err := s.db.QueryRowContext(ctx, query, tokenID, since).Scan(&minPrice, &maxPrice); err != nil {
+		return 0, 0, fmt.Errorf("failed to get price range: %w", err)
+	}
+
+	if !minPrice.Valid || !maxPrice.Valid {
+		return 0, 0, sql.ErrNoRows
+	}
+
+	return minPrice.Float64, maxPrice.Float64, nil
+}
+
+// GetPriceOHLC retrieves the open, high, low and close price of a token recorded in [since, until)
+func (s *Storage) GetPriceOHLC(ctx context.Context, tokenID string, since, until time.Time) (*PriceOHLC, error) {
+	query := `
+		SELECT
+			(SELECT price FROM token_prices WHERE token_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at ASC LIMIT 1),
+			MAX(price),
+			MIN(price),
+			(SELECT price FROM token_prices WHERE token_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at DESC LIMIT 1)
+		FROM token_prices
+		WHERE token_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+	`
+
+	var open, high, low, closePrice sql.NullFloat64
+	if err := s.db.QueryRowContext(ctx, query, tokenID, since, until).Scan(&open, &high, &low, &closePrice); err != nil {
+		return nil, fmt.Errorf("failed to get price OHLC: %w", err)
+	}
+
+	if !open.Valid || !closePrice.Valid {
+		return nil, sql.ErrNoRows
+	}
+
+	return &PriceOHLC{Open: open.Float64, High: high.Float64, Low: low.Float64, Close: closePrice.Float64}, nil
+}
+
 // CleanupOldPrices deletes token prices older than the specified duration
 func (s *Storage) CleanupOldPrices(ctx context.Context, olderThan time.Duration) error {
 	query := `DELETE FROM token_prices WHERE recorded_at < NOW() - $1::interval`
@@ -109,16 +158,16 @@ func (s *Storage) CleanupOldPrices(ctx context.Context, olderThan time.Duration)
 	return nil
 }
 
-// GetPriceHistory retrieves price history for a market within a time range
-func (s *Storage) GetPriceHistory(ctx context.Context, marketID string, since time.Time) ([]TokenPrice, error) {
+// GetPriceHistory retrieves price history for a token within a time range
+func (s *Storage) GetPriceHistory(ctx context.Context, tokenID string, since time.Time) ([]TokenPrice, error) {
 	query := `
-		SELECT id, token_id, market_id, price, side, size, recorded_at
+		SELECT id, token_id, market_id, price, side, size, traded_at, recorded_at
 		FROM token_prices
-		WHERE market_id = $1 AND recorded_at >= $2
+		WHERE token_id = $1 AND recorded_at >= $2
 		ORDER BY recorded_at ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, marketID, since)
+	rows, err := s.db.QueryContext(ctx, query, tokenID, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get price history: %w", err)
 	}
@@ -127,7 +176,7 @@ func (s *Storage) GetPriceHistory(ctx context.Context, marketID string, since ti
 	var prices []TokenPrice
 	for rows.Next() {
 		var price TokenPrice
-		if err := rows.Scan(&price.ID, &price.TokenID, &price.MarketID, &price.Price, &price.Side, &price.Size, &price.RecordedAt); err != nil {
+		if err := rows.Scan(&price.ID, &price.TokenID, &price.MarketID, &price.Price, &price.Side, &price.Size, &price.TradedAt, &price.RecordedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan price: %w", err)
 		}
 		prices = append(prices, price)
@@ -7,6 +7,16 @@ import (
 	"time"
 )
 
+// userColumns is the column list shared by every query that returns full user rows
+const userColumns = `id, telegram_id, username, timezone, quiet_start, quiet_end, urgent_threshold_pct, digest_mode, last_digest_at, cutoff_reminders, created_at, updated_at`
+
+// scanUser scans a row selected with userColumns into a User
+func scanUser(row rowScanner, user *User) error {
+	return row.Scan(
+		&user.ID, &user.TelegramID, &user.Username, &user.Timezone, &user.QuietStart, &user.QuietEnd, &user.UrgentThresholdPct, &user.DigestMode, &user.LastDigestAt, &user.CutoffReminders, &user.CreatedAt, &user.UpdatedAt,
+	)
+}
+
 // CreateOrGetUser creates a new user or returns existing one by Telegram ID
 func (s *Storage) CreateOrGetUser(ctx context.Context, telegramID int64, username string) (*User, error) {
 	// Try to get existing user first
@@ -30,17 +40,14 @@ func (s *Storage) CreateOrGetUser(ctx context.Context, telegramID int64, usernam
 	query := `
 		INSERT INTO users (telegram_id, username, created_at, updated_at)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, telegram_id, username, created_at, updated_at
-	`
+		RETURNING ` + userColumns
 
 	now := time.Now()
 	user = &User{}
-	err = s.db.QueryRowContext(
+	err = scanUser(s.db.QueryRowContext(
 		ctx, query,
 		telegramID, username, now, now,
-	).Scan(
-		&user.ID, &user.TelegramID, &user.Username, &user.CreatedAt, &user.UpdatedAt,
-	)
+	), user)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
@@ -52,12 +59,10 @@ func (s *Storage) CreateOrGetUser(ctx context.Context, telegramID int64, usernam
 
 // GetUserByTelegramID retrieves a user by their Telegram ID
 func (s *Storage) GetUserByTelegramID(ctx context.Context, telegramID int64) (*User, error) {
-	query := `SELECT id, telegram_id, username, created_at, updated_at FROM users WHERE telegram_id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE telegram_id = $1`
 
 	user := &User{}
-	err := s.db.QueryRowContext(ctx, query, telegramID).Scan(
-		&user.ID, &user.TelegramID, &user.Username, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err := scanUser(s.db.QueryRowContext(ctx, query, telegramID), user)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -71,12 +76,10 @@ func (s *Storage) GetUserByTelegramID(ctx context.Context, telegramID int64) (*U
 
 // GetUserByID retrieves a user by their internal ID
 func (s *Storage) GetUserByID(ctx context.Context, id int64) (*User, error) {
-	query := `SELECT id, telegram_id, username, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
 	user := &User{}
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.TelegramID, &user.Username, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err := scanUser(s.db.QueryRowContext(ctx, query, id), user)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -104,3 +107,114 @@ func (s *Storage) UpdateUser(ctx context.Context, user *User) error {
 
 	return nil
 }
+
+// UpdateUserSettings updates a user's timezone, quiet hours, urgent threshold and digest mode.
+// Turning digest mode off releases the alerts collected for the next digest
+func (s *Storage) UpdateUserSettings(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users
+		SET timezone = $1, quiet_start = $2, quiet_end = $3, urgent_threshold_pct = $4, digest_mode = $5, cutoff_reminders = $6, updated_at = $7
+		WHERE id = $8
+	`
+
+	user.UpdatedAt = time.Now()
+	_, err := s.db.ExecContext(ctx, query, user.Timezone, user.QuietStart, user.QuietEnd, user.UrgentThresholdPct, user.DigestMode, user.CutoffReminders, user.UpdatedAt, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user settings: %w", err)
+	}
+
+	// Alerts already collected for a digest go out in the next deferred summary once digests are off
+	if !user.HasDigest() {
+		if _, err := s.ReleaseDigestAlerts(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetUsersWithDeferredAlerts retrieves the users that have alerts held back during quiet hours
+func (s *Storage) GetUsersWithDeferredAlerts(ctx context.Context) ([]User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id IN (
+			SELECT a.user_id
+			FROM alert_history h
+			JOIN alerts a ON a.id = h.alert_id
+			WHERE h.delivery = $1 AND h.message_sent = false
+		)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, DeliveryDeferred)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users with deferred alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// GetDigestUsers retrieves the users that have hourly or daily digests enabled
+func (s *Storage) GetDigestUsers(ctx context.Context) ([]User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE digest_mode IN ($1, $2)`
+
+	rows, err := s.db.QueryContext(ctx, query, DigestHourly, DigestDaily)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// UpdateLastDigestAt records the end of the last period a digest was compiled for
+func (s *Storage) UpdateLastDigestAt(ctx context.Context, userID int64, periodEnd time.Time) error {
+	query := `UPDATE users SET last_digest_at = $1 WHERE id = $2`
+
+	if _, err := s.db.ExecContext(ctx, query, periodEnd, userID); err != nil {
+		return fmt.Errorf("failed to update last digest time: %w", err)
+	}
+
+	return nil
+}
+
+// GetReminderUsers retrieves the users that opted in to cutoff reminders
+func (s *Storage) GetReminderUsers(ctx context.Context) ([]User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE cutoff_reminders <> ''`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminder users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
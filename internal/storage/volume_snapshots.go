@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StoreVolumeSnapshot stores the traded volume of a token's market as reported now
+func (s *Storage) StoreVolumeSnapshot(ctx context.Context, tokenID, marketID string, volume, volume24h float64) error {
+	query := `
+		INSERT INTO volume_snapshots (token_id, market_id, volume, volume_24h, recorded_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, tokenID, marketID, volume, volume24h, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to store volume snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetVolumeWindowAgo retrieves the volume snapshot recorded closest to one lookback window ago for a given token.
+// Like GetPriceWindowAgo, only snapshots within a sixth of the window around that point are considered.
+func (s *Storage) GetVolumeWindowAgo(ctx context.Context, tokenID string, window time.Duration) (*VolumeSnapshot, error) {
+	query := `
+		SELECT id, token_id, market_id, volume, volume_24h, recorded_at
+		FROM volume_snapshots
+		WHERE token_id = $1
+		  AND recorded_at >= NOW() - ($2::float8 + $3::float8) * INTERVAL '1 second'
+		  AND recorded_at <= NOW() - ($2::float8 - $3::float8) * INTERVAL '1 second'
+		ORDER BY ABS(EXTRACT(EPOCH FROM (recorded_at - (NOW() - $2::float8 * INTERVAL '1 second')))) ASC
+		LIMIT 1
+	`
+
+	tolerance := window / 6
+
+	snapshot := &VolumeSnapshot{}
+	err := s.db.QueryRowContext(ctx, query, tokenID, window.Seconds(), tolerance.Seconds()).Scan(
+		&snapshot.ID,
+		&snapshot.TokenID,
+		&snapshot.MarketID,
+		&snapshot.Volume,
+		&snapshot.Volume24h,
+		&snapshot.RecordedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get volume from %v ago: %w", window, err)
+	}
+
+	return snapshot, nil
+}
+
+// CleanupOldVolumes deletes volume snapshots older than the specified duration
+func (s *Storage) CleanupOldVolumes(ctx context.Context, olderThan time.Duration) error {
+	query := `DELETE FROM volume_snapshots WHERE recorded_at < NOW() - $1::interval`
+
+	result, err := s.db.ExecContext(ctx, query, olderThan.String())
+	if err != nil {
+		return fmt.Errorf("failed to cleanup old volumes: %w", err)
+	}
+
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		s.log.Warnf("Failed to get rows affected during cleanup: %v", err)
+		return nil
+	}
+
+	if rowsDeleted > 0 {
+		s.log.Debugf("Cleaned up %d old volume snapshots", rowsDeleted)
+	}
+
+	return nil
+}
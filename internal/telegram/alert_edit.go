@@ -0,0 +1,228 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// handleEditAlertCallback shows the settings card of an alert
+func (b *Bot) handleEditAlertCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alert := b.beginAlertEdit(ctx, callback)
+	if alert == nil {
+		return
+	}
+
+	msg := tgbotapi.NewEditMessageText(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		FormatAlertSettings(alert, b.defaultCooldown),
+	)
+	msg.ParseMode = "HTML"
+	keyboard := BuildEditAlertMenu(alert)
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleEditThresholdCallback asks for the new threshold of an alert
+func (b *Bot) handleEditThresholdCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alert := b.beginAlertEdit(ctx, callback)
+	if alert == nil {
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	state.Step = "awaiting_threshold"
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, thresholdPrompt(alert.ThresholdUnit))
+	keyboard := BuildThresholdSelectionMenu(alert.ThresholdUnit)
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleEditDirectionCallback asks for the new direction of an alert
+func (b *Bot) handleEditDirectionCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	if alert := b.beginAlertEdit(ctx, callback); alert == nil {
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	state.Step = "awaiting_direction"
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgDirectionPrompt)
+	keyboard := BuildDirectionSelectionMenu()
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleEditWindowCallback asks for the new lookback window of an alert
+func (b *Bot) handleEditWindowCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	if alert := b.beginAlertEdit(ctx, callback); alert == nil {
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	state.Step = "awaiting_window"
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgWindowPrompt)
+	keyboard := BuildWindowSelectionMenu()
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleEditFiltersCallback shows the noise filters of an alert
+func (b *Bot) handleEditFiltersCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alert := b.beginAlertEdit(ctx, callback)
+	if alert == nil {
+		return
+	}
+
+	msg := tgbotapi.NewEditMessageText(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		fmt.Sprintf("<b>Noise filters:</b> %s\n\n%s", FormatNoiseFilters(alert), MsgFiltersPrompt),
+	)
+	msg.ParseMode = "HTML"
+	keyboard := BuildNoiseFilterMenu(alert)
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleNoiseFilterCallback sets the minimum trade size or confirmation polls of an alert
+// (e.g., "filter_size_123_100" or "filter_polls_123_3")
+func (b *Bot) handleNoiseFilterCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 4 {
+		b.log.Errorf("Invalid noise filter callback data: %s", callback.Data)
+		return
+	}
+
+	alertID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		b.log.Errorf("Invalid alert ID in callback: %s", parts[2])
+		return
+	}
+	value, err := strconv.Atoi(parts[3])
+	if err != nil || value < 0 {
+		b.log.Errorf("Invalid noise filter value in callback: %s", parts[3])
+		return
+	}
+
+	b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+	b.applyAlertEdit(ctx, callback.Message.Chat.ID, callback.From.ID, alertID, func(alert *storage.Alert) {
+		if strings.HasPrefix(callback.Data, CallbackFilterSize+"_") {
+			alert.MinTradeSize = float64(value)
+		} else {
+			alert.ConfirmPolls = max(value, 1)
+		}
+	})
+}
+
+// beginAlertEdit loads the alert referenced by an edit callback (e.g., "edit_alert_123") and resets the
+// user's state to edit it. Returns nil after notifying the user if the alert can't be edited
+func (b *Bot) beginAlertEdit(ctx context.Context, callback *tgbotapi.CallbackQuery) *storage.Alert {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid edit callback data: %s", callback.Data)
+		return nil
+	}
+
+	alertID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		b.log.Errorf("Invalid alert ID in callback: %s", parts[2])
+		return nil
+	}
+
+	alert, err := b.getUserAlert(ctx, callback.From.ID, alertID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(callback.Message.Chat.ID, MsgAlertNotFound, BuildMainMenu())
+			return nil
+		}
+		b.log.Errorf("Failed to get alert %d: %v", alertID, err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return nil
+	}
+
+	b.clearUserState(callback.From.ID)
+	state := b.getUserState(callback.From.ID)
+	state.MarketID = alert.MarketID
+	state.Data["edit_alert_id"] = alert.ID
+	state.Data["threshold_unit"] = alert.ThresholdUnit
+
+	return alert
+}
+
+// getUserAlert retrieves an alert owned by the given Telegram user
+func (b *Bot) getUserAlert(ctx context.Context, telegramID, alertID int64) (*storage.Alert, error) {
+	user, err := b.storage.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, err
+	}
+
+	alert, err := b.storage.GetAlert(ctx, alertID)
+	if err != nil {
+		return nil, err
+	}
+
+	if alert.UserID != user.ID {
+		return nil, sql.ErrNoRows
+	}
+
+	return alert, nil
+}
+
+// editingAlertID returns the alert being edited in the user's state, if any
+func editingAlertID(state *UserState) (int64, bool) {
+	alertID, ok := state.Data["edit_alert_id"].(int64)
+	return alertID, ok
+}
+
+// applyAlertEdit updates the alert being edited with the given change and shows its settings card again
+func (b *Bot) applyAlertEdit(ctx context.Context, chatID, userID int64, alertID int64, change func(alert *storage.Alert)) {
+	b.clearUserState(userID)
+
+	alert, err := b.getUserAlert(ctx, userID, alertID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(chatID, MsgAlertNotFound, BuildMainMenu())
+			return
+		}
+		b.log.Errorf("Failed to get alert %d: %v", alertID, err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	change(alert)
+
+	if err := b.storage.UpdateAlertSettings(ctx, alert); err != nil {
+		b.log.Errorf("Failed to update alert %d: %v", alertID, err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	b.SendMessage(chatID, MsgAlertUpdated+"\n\n"+FormatAlertSettings(alert, b.defaultCooldown), BuildEditAlertMenu(alert))
+}
+
+// setThreshold returns an edit that sets an alert's threshold in the given unit
+func setThreshold(unit string, threshold float64) func(alert *storage.Alert) {
+	return func(alert *storage.Alert) {
+		alert.ThresholdUnit = unit
+		alert.ThresholdPct = 0
+		alert.ThresholdCents = 0
+		alert.ThresholdSigma = 0
+		switch unit {
+		case storage.ThresholdUnitCents:
+			alert.ThresholdCents = threshold
+		case storage.ThresholdUnitSigma:
+			alert.ThresholdSigma = threshold
+		default:
+			alert.ThresholdPct = threshold
+		}
+	}
+}
@@ -2,43 +2,80 @@ package telegram
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // showMyAlerts displays the user's alerts grouped by market
 func (b *Bot) showMyAlerts(ctx context.Context, chatID, userID int64) {
-	// Get user from database
-	user, err := b.storage.GetUserByTelegramID(ctx, userID)
+	message, keyboard, err := b.buildAlertsList(ctx, userID)
 	if err != nil {
-		b.log.Errorf("Failed to get user: %v", err)
+		b.log.Errorf("Failed to build alerts list: %v", err)
 		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
 		return
 	}
 
+	b.SendMessage(chatID, message, keyboard)
+}
+
+// buildAlertsList formats the user's alerts, including paused ones, and the matching menu
+func (b *Bot) buildAlertsList(ctx context.Context, userID int64) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	// Get user from database
+	user, err := b.storage.GetUserByTelegramID(ctx, userID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
 	// Get all user's alerts
 	alerts, err := b.storage.GetAlertsByUserID(ctx, user.ID)
 	if err != nil {
-		b.log.Errorf("Failed to get alerts: %v", err)
-		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
-		return
+		return "", tgbotapi.InlineKeyboardMarkup{}, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	markets, err := b.storage.GetMarketsByUserID(ctx, user.ID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, fmt.Errorf("failed to get markets: %w", err)
 	}
 
 	// Group alerts by market
+	now := time.Now()
 	alertsByMarket := make(map[string][]AlertInfo)
 	for _, alert := range alerts {
-		if !alert.IsActive {
-			continue
-		}
-
 		alertInfo := AlertInfo{
-			ID:           alert.ID,
-			MarketID:     alert.MarketID,
-			MarketName:   alert.MarketName,
-			ThresholdPct: alert.ThresholdPct,
+			ID:             alert.ID,
+			MarketID:       alert.MarketID,
+			MarketName:     alert.MarketName,
+			OutcomeName:    alert.OutcomeName,
+			AlertType:      alert.AlertType,
+			Threshold:      alert.ThresholdValue(),
+			ThresholdUnit:  alert.ThresholdUnit,
+			Direction:      alert.Direction,
+			Window:         alert.Window(),
+			LevelDirection: alert.LevelDirection,
+			VolumeMultiple: alert.VolumeMultiple,
+			PrintNotional:  alert.PrintNotional,
+			SpreadCents:    alert.SpreadCents,
+			MinLiquidity:   alert.MinLiquidity,
+			BandCents:      alert.BandCents,
+			Paused:         !alert.IsActive,
+			Muted:          alert.IsMuted(now),
+			Archived:       alert.IsArchived(),
+		}
+		if market, ok := markets[alert.MarketID]; ok {
+			alertInfo.MarketStatus = market.StatusLabel(now)
+		}
+		if alert.LevelPrice != nil {
+			alertInfo.LevelPrice = *alert.LevelPrice
 		}
 		alertsByMarket[alert.MarketID] = append(alertsByMarket[alert.MarketID], alertInfo)
 	}
 
-	// Format and send the message
+	// Format the message
 	message := FormatAlertsList(alertsByMarket)
 	keyboard := BuildMainMenu()
 
@@ -46,5 +83,106 @@ func (b *Bot) showMyAlerts(ctx context.Context, chatID, userID int64) {
 		keyboard = BuildAlertListMenu(alertsByMarket)
 	}
 
-	b.SendMessage(chatID, message, keyboard)
+	return message, keyboard, nil
+}
+
+// refreshMyAlerts redraws the alerts list in the message the callback came from, with a status line on top
+func (b *Bot) refreshMyAlerts(ctx context.Context, callback *tgbotapi.CallbackQuery, status string) {
+	message, keyboard, err := b.buildAlertsList(ctx, callback.From.ID)
+	if err != nil {
+		b.log.Errorf("Failed to build alerts list: %v", err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, status+"\n\n"+message)
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handlePauseAlertCallback pauses an alert without deleting it
+func (b *Bot) handlePauseAlertCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	b.setAlertStatus(ctx, callback, false)
+}
+
+// handleResumeAlertCallback resumes a paused alert
+func (b *Bot) handleResumeAlertCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	b.setAlertStatus(ctx, callback, true)
+}
+
+// setAlertStatus pauses or resumes the alert referenced by the callback (e.g., "pause_alert_123")
+func (b *Bot) setAlertStatus(ctx context.Context, callback *tgbotapi.CallbackQuery, isActive bool) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid alert status callback data: %s", callback.Data)
+		return
+	}
+
+	alertID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		b.log.Errorf("Invalid alert ID in callback: %s", parts[2])
+		return
+	}
+
+	alert, err := b.getUserAlert(ctx, callback.From.ID, alertID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(callback.Message.Chat.ID, MsgAlertNotFound, BuildMainMenu())
+			return
+		}
+		b.log.Errorf("Failed to get alert %d: %v", alertID, err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	if isActive && alert.IsArchived() {
+		b.refreshMyAlerts(ctx, callback, MsgAlertArchived)
+		return
+	}
+
+	// A resumed alert counts toward the market limit again
+	if isActive && !alert.IsActive {
+		if err := b.storage.CheckMarketLimit(ctx, alert.UserID, alert.MarketID); err != nil {
+			if strings.Contains(err.Error(), "cannot track more than") {
+				b.refreshMyAlerts(ctx, callback, MsgMaxMarketsReached)
+				return
+			}
+			b.log.Errorf("Failed to check market limit: %v", err)
+			b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+			return
+		}
+	}
+
+	if err := b.storage.UpdateAlertStatus(ctx, alert.ID, isActive); err != nil {
+		b.log.Errorf("Failed to update status of alert %d: %v", alert.ID, err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	status := MsgAlertPaused
+	if isActive {
+		status = MsgAlertResumed
+	}
+	b.refreshMyAlerts(ctx, callback, status)
+}
+
+// handlePauseAllCallback pauses every active alert of the user
+func (b *Bot) handlePauseAllCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	user, err := b.storage.GetUserByTelegramID(ctx, callback.From.ID)
+	if err != nil {
+		b.log.Errorf("Failed to get user: %v", err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	paused, err := b.storage.PauseUserAlerts(ctx, user.ID)
+	if err != nil {
+		b.log.Errorf("Failed to pause alerts: %v", err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	b.refreshMyAlerts(ctx, callback, fmt.Sprintf(MsgAlertsPaused, paused))
 }
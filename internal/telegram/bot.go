@@ -2,7 +2,10 @@ package telegram
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/qmitry/opinion-alert-bot/internal/api"
@@ -13,10 +16,14 @@ import (
 // Bot represents the Telegram bot
 type Bot struct {
 	api       *tgbotapi.BotAPI
+	sender    *Sender
 	storage   *storage.Storage
 	apiClient *api.Client
 	log       *logrus.Logger
 
+	// Cooldown applied to alerts without their own cooldown
+	defaultCooldown time.Duration
+
 	// User conversation states
 	userStates map[int64]*UserState
 	stateMu    sync.RWMutex
@@ -31,7 +38,7 @@ type UserState struct {
 }
 
 // NewBot creates a new Telegram bot instance
-func NewBot(token string, storage *storage.Storage, apiClient *api.Client, log *logrus.Logger) (*Bot, error) {
+func NewBot(token string, storage *storage.Storage, apiClient *api.Client, defaultCooldown time.Duration, log *logrus.Logger) (*Bot, error) {
 	botAPI, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
@@ -45,6 +52,8 @@ func NewBot(token string, storage *storage.Storage, apiClient *api.Client, log *
 		{Command: "help", Description: "Show help message"},
 		{Command: "create", Description: "Create new alert"},
 		{Command: "alerts", Description: "View my alerts"},
+		{Command: "history", Description: "View triggered alerts"},
+		{Command: "settings", Description: "Timezone and quiet hours"},
 	}
 
 	commandConfig := tgbotapi.NewSetMyCommands(commands...)
@@ -53,11 +62,13 @@ func NewBot(token string, storage *storage.Storage, apiClient *api.Client, log *
 	}
 
 	return &Bot{
-		api:        botAPI,
-		storage:    storage,
-		apiClient:  apiClient,
-		log:        log,
-		userStates: make(map[int64]*UserState),
+		api:             botAPI,
+		sender:          NewSender(botAPI, log),
+		storage:         storage,
+		apiClient:       apiClient,
+		log:             log,
+		defaultCooldown: defaultCooldown,
+		userStates:      make(map[int64]*UserState),
 	}, nil
 }
 
@@ -68,6 +79,9 @@ func (b *Bot) Start(ctx context.Context) error {
 
 	updates := b.api.GetUpdatesChan(u)
 
+	// All outgoing messages go through the rate limited send queue
+	go b.sender.Start(ctx)
+
 	b.log.Info("Telegram bot started, waiting for updates...")
 
 	for {
@@ -133,17 +147,26 @@ func (b *Bot) SendMessage(chatID int64, text string, keyboard interface{}) error
 		msg.ReplyMarkup = keyboard
 	}
 
-	_, err := b.api.Send(msg)
+	_, err := b.send(msg)
 	return err
 }
 
+// send queues a reply, menu or message edit behind any pending alert notifications
+func (b *Bot) send(msg tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return b.sender.Send(context.Background(), msg, PriorityMenu)
+}
+
 // SendAlertNotification sends a price alert notification to a user
-func (b *Bot) SendAlertNotification(chatID int64, message string) error {
+func (b *Bot) SendAlertNotification(ctx context.Context, chatID int64, message string, keyboard interface{}) error {
 	msg := tgbotapi.NewMessage(chatID, message)
 	msg.ParseMode = "HTML"
 	msg.DisableWebPagePreview = true
 
-	_, err := b.api.Send(msg)
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+
+	_, err := b.sender.Send(ctx, msg, PriorityAlert)
 	if err != nil {
 		b.log.Errorf("Failed to send alert notification to chat %d: %v", chatID, err)
 		return err
@@ -152,3 +175,18 @@ func (b *Bot) SendAlertNotification(chatID int64, message string) error {
 	b.log.Debugf("Sent alert notification to chat %d", chatID)
 	return nil
 }
+
+// IsBlockedError reports whether a send failed because the user blocked the bot or the chat is gone
+func IsBlockedError(err error) bool {
+	var tgErr *tgbotapi.Error
+	return errors.As(err, &tgErr) && tgErr.Code == http.StatusForbidden
+}
+
+// RetryAfter returns how long Telegram asked to wait before sending again when a send was rate limited
+func RetryAfter(err error) (time.Duration, bool) {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusTooManyRequests || tgErr.RetryAfter <= 0 {
+		return 0, false
+	}
+	return time.Duration(tgErr.RetryAfter) * time.Second, true
+}
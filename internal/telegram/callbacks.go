@@ -30,12 +30,79 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, callback *tgbotapi.Callba
 		b.handleHelpCallback(ctx, callback)
 	case strings.HasPrefix(data, CallbackSelectMarket+"_"):
 		b.handleSelectMarketCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectOutcome+"_"):
+		b.handleSelectOutcomeCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectType+"_"):
+		b.handleSelectTypeCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectLevel+"_"):
+		b.handleSelectLevelCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectMultiple+"_"):
+		b.handleSelectMultipleCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectTrade+"_"):
+		b.handleSelectTradeCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectSpread+"_"):
+		b.handleSelectSpreadCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectLiquidity+"_"):
+		b.handleSelectLiquidityCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectBand+"_"):
+		b.handleSelectBandCallback(ctx, callback)
 	case data == CallbackCustomMarket:
 		b.handleCustomMarketCallback(ctx, callback)
-	case strings.HasPrefix(data, CallbackSelectThreshold+"_"):
+	case strings.HasPrefix(data, CallbackSelectThreshold+"_"), strings.HasPrefix(data, CallbackSelectCents+"_"), strings.HasPrefix(data, CallbackSelectSigma+"_"):
 		b.handleSelectThresholdCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackThresholdUnit+"_"):
+		b.handleThresholdUnitCallback(ctx, callback)
 	case data == CallbackCustomThreshold:
 		b.handleCustomThresholdCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectDirection+"_"):
+		b.handleSelectDirectionCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectWindow+"_"):
+		b.handleSelectWindowCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSelectCooldown+"_"):
+		b.handleSelectCooldownCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackEditAlert+"_"):
+		b.handleEditAlertCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackEditThreshold+"_"):
+		b.handleEditThresholdCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackEditDirection+"_"):
+		b.handleEditDirectionCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackEditWindow+"_"):
+		b.handleEditWindowCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackEditFilters+"_"):
+		b.handleEditFiltersCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackFilterSize+"_"), strings.HasPrefix(data, CallbackFilterPolls+"_"):
+		b.handleNoiseFilterCallback(ctx, callback)
+	case data == CallbackSettings:
+		b.clearUserState(callback.From.ID)
+		b.showSettings(ctx, callback.Message.Chat.ID, callback.Message.MessageID, callback.From.ID, "")
+	case data == CallbackSetTimezone:
+		b.handleSetTimezoneCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackQuietHours+"_"):
+		b.handleQuietHoursCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackUrgentThreshold+"_"):
+		b.handleUrgentThresholdCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackDigestMode+"_"):
+		b.handleDigestModeCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackCutoffReminders+"_"):
+		b.handleCutoffRemindersCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackSnooze+"_"):
+		b.handleSnoozeCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackRaiseThreshold+"_"):
+		b.handleRaiseThresholdCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackMuteTomorrow+"_"):
+		b.handleMuteTomorrowCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackUnmuteAlert+"_"):
+		b.handleUnmuteAlertCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackAlertHistory+"_"):
+		b.handleAlertHistoryCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackHistory+"_"):
+		b.handleHistoryCallback(ctx, callback)
+	case data == CallbackPauseAll:
+		b.handlePauseAllCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackPauseAlert+"_"):
+		b.handlePauseAlertCallback(ctx, callback)
+	case strings.HasPrefix(data, CallbackResumeAlert+"_"):
+		b.handleResumeAlertCallback(ctx, callback)
 	case strings.HasPrefix(data, CallbackDeleteAlert+"_"):
 		b.handleDeleteAlertCallback(ctx, callback)
 	case strings.HasPrefix(data, CallbackConfirmDelete+"_"):
@@ -51,6 +118,8 @@ func (b *Bot) handleCallbackQuery(ctx context.Context, callback *tgbotapi.Callba
 
 // handleCreateAlertCallback initiates the alert creation flow
 func (b *Bot) handleCreateAlertCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	b.clearUserState(callback.From.ID)
+
 	// Fetch random tracked markets from database
 	randomMarkets, err := b.storage.GetRandomTrackedMarkets(ctx, 5)
 	if err != nil {
@@ -75,7 +144,7 @@ func (b *Bot) handleCreateAlertCallback(ctx context.Context, callback *tgbotapi.
 	)
 	keyboard := BuildMarketSelectionMenu(featuredMarkets)
 	msg.ReplyMarkup = &keyboard
-	b.api.Send(msg)
+	b.send(msg)
 }
 
 // handleSelectMarketCallback handles selection of a featured market
@@ -96,28 +165,150 @@ func (b *Bot) handleSelectMarketCallback(ctx context.Context, callback *tgbotapi
 		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, MsgMarketNotFound)
 		keyboard := BuildBackButton()
 		msg.ReplyMarkup = keyboard
-		b.api.Send(msg)
+		b.send(msg)
+		return
+	}
+
+	// Delete the market selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
+
+	// Ask for the outcome (multi-outcome markets) or the threshold
+	b.startAlertSetup(ctx, callback.Message.Chat.ID, callback.From.ID, marketID, marketDetails,
+		fmt.Sprintf("Market selected: <b>%s</b>", marketDetails.MarketTitle))
+}
+
+// handleSelectOutcomeCallback handles selection of an outcome of a multi-outcome market
+func (b *Bot) handleSelectOutcomeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract outcome index from callback data (format: "select_outcome_2" or "select_outcome_all")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid select outcome callback data: %s", callback.Data)
 		return
 	}
 
-	// Store market ID, name, and token ID (automatically set for both binary and multi-outcome)
 	state := b.getUserState(callback.From.ID)
-	state.MarketID = marketID
-	state.Data["market_name"] = marketDetails.MarketTitle
-	state.Data["token_id"] = marketDetails.YesTokenID
-	state.Step = "awaiting_threshold"
+	available, _ := state.Data["available_outcomes"].([]OutcomeOption)
+	if state.MarketID == "" || len(available) == 0 {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
 
-	// Delete the market selection message
+	var selected []OutcomeOption
+	if parts[2] == OutcomeAll {
+		selected = available
+	} else {
+		index, err := strconv.Atoi(parts[2])
+		if err != nil || index < 0 || index >= len(available) {
+			b.log.Errorf("Invalid outcome index in callback: %s", parts[2])
+			return
+		}
+		selected = []OutcomeOption{available[index]}
+	}
+
+	state.Data["outcomes"] = selected
+	state.Step = "awaiting_type"
+
+	// Delete the outcome selection message
 	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	b.api.Send(deleteMsg)
+	b.send(deleteMsg)
 
-	// Send threshold prompt with quick-select buttons
-	confirmMsg := fmt.Sprintf("Market selected: <b>%s</b>\n\n%s", marketDetails.MarketTitle, MsgThresholdPrompt)
+	// Ask for the alert type
+	outcomeLabel := "All outcomes"
+	if len(selected) == 1 {
+		outcomeLabel = selected[0].Name
+	}
+	confirmMsg := fmt.Sprintf("Outcome selected: <b>%s</b>\n\n%s", outcomeLabel, MsgAlertTypePrompt)
 	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, confirmMsg)
 	msg.ParseMode = "HTML"
 	msg.DisableWebPagePreview = true
-	msg.ReplyMarkup = BuildThresholdSelectionMenu()
-	b.api.Send(msg)
+	msg.ReplyMarkup = BuildAlertTypeSelectionMenu()
+	b.send(msg)
+}
+
+// handleSelectTypeCallback handles the choice of the alert type
+func (b *Bot) handleSelectTypeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract alert type from callback data (format: "select_type_spike")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid select type callback data: %s", callback.Data)
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state.MarketID == "" {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	var msg tgbotapi.EditMessageTextConfig
+	switch parts[2] {
+	case storage.AlertTypeSpike:
+		state.Data["alert_type"] = storage.AlertTypeSpike
+		state.Step = "awaiting_threshold"
+		msg = tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, thresholdPrompt(thresholdUnit(state)))
+		keyboard := BuildThresholdSelectionMenu(thresholdUnit(state))
+		msg.ReplyMarkup = &keyboard
+	case storage.AlertTypeCrossing:
+		state.Data["alert_type"] = storage.AlertTypeCrossing
+		state.Step = "awaiting_level"
+		msg = tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgLevelPrompt)
+	case storage.AlertTypeVolume:
+		state.Data["alert_type"] = storage.AlertTypeVolume
+		state.Step = "awaiting_volume_multiple"
+		msg = tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgMultiplePrompt)
+		keyboard := BuildVolumeMultipleMenu()
+		msg.ReplyMarkup = &keyboard
+	case storage.AlertTypeSpread:
+		state.Data["alert_type"] = storage.AlertTypeSpread
+		state.Step = "awaiting_spread"
+		msg = tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgSpreadPrompt)
+		keyboard := BuildSpreadMenu()
+		msg.ReplyMarkup = &keyboard
+	case storage.AlertTypeLiquidity:
+		state.Data["alert_type"] = storage.AlertTypeLiquidity
+		state.Step = "awaiting_liquidity"
+		msg = tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgLiquidityPrompt)
+		keyboard := BuildLiquidityMenu()
+		msg.ReplyMarkup = &keyboard
+	case storage.AlertTypeConsistency:
+		state.Data["alert_type"] = storage.AlertTypeConsistency
+		state.Step = "awaiting_band"
+		msg = tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgBandPrompt)
+		keyboard := BuildBandMenu()
+		msg.ReplyMarkup = &keyboard
+	default:
+		b.log.Errorf("Unknown alert type in callback: %s", parts[2])
+		return
+	}
+	b.send(msg)
+}
+
+// handleSelectLevelCallback processes the crossing direction of a level alert and creates the alert
+func (b *Bot) handleSelectLevelCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract direction from callback data (format: "select_level_above")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 || (parts[2] != storage.CrossAbove && parts[2] != storage.CrossBelow) {
+		b.log.Errorf("Invalid select level callback data: %s", callback.Data)
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if _, ok := state.Data["level_price"].(float64); !ok || state.MarketID == "" {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+	state.Data["level_direction"] = parts[2]
+
+	// Delete the direction selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
+
+	// Create the alert
+	b.createAlert(ctx, callback.Message.Chat.ID, callback.From.ID, state)
 }
 
 // handleCustomMarketCallback prompts user to enter custom market ID
@@ -127,13 +318,13 @@ func (b *Bot) handleCustomMarketCallback(ctx context.Context, callback *tgbotapi
 
 	// Delete the market selection message
 	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	b.api.Send(deleteMsg)
+	b.send(deleteMsg)
 
 	// Send market ID prompt
 	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, MsgMarketIDPrompt)
 	msg.ParseMode = "HTML"
 	msg.DisableWebPagePreview = true
-	b.api.Send(msg)
+	b.send(msg)
 }
 
 // handleMyAlertsCallback shows the user's alerts
@@ -152,7 +343,7 @@ func (b *Bot) handleHelpCallback(ctx context.Context, callback *tgbotapi.Callbac
 	msg.ParseMode = "HTML"
 	keyboard := BuildBackButton()
 	msg.ReplyMarkup = &keyboard
-	b.api.Send(msg)
+	b.send(msg)
 }
 
 // handleDeleteAlertCallback shows delete confirmation
@@ -177,7 +368,7 @@ func (b *Bot) handleDeleteAlertCallback(ctx context.Context, callback *tgbotapi.
 	)
 	keyboard := BuildConfirmDeleteMenu(alertID)
 	msg.ReplyMarkup = &keyboard
-	b.api.Send(msg)
+	b.send(msg)
 }
 
 // handleConfirmDeleteCallback deletes the alert
@@ -214,11 +405,11 @@ func (b *Bot) handleConfirmDeleteCallback(ctx context.Context, callback *tgbotap
 	// Show success message and return to alerts list
 	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, MsgAlertDeleted)
 	msg.ReplyMarkup = BuildMainMenu()
-	b.api.Send(msg)
+	b.send(msg)
 
 	// Delete the confirmation message
 	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	b.api.Send(deleteMsg)
+	b.send(deleteMsg)
 }
 
 // handleCancelDeleteCallback cancels alert deletion
@@ -230,7 +421,7 @@ func (b *Bot) handleCancelDeleteCallback(ctx context.Context, callback *tgbotapi
 	)
 	keyboard := BuildBackButton()
 	msg.ReplyMarkup = &keyboard
-	b.api.Send(msg)
+	b.send(msg)
 }
 
 // handleBackToMenuCallback returns to the main menu
@@ -244,12 +435,12 @@ func (b *Bot) handleBackToMenuCallback(ctx context.Context, callback *tgbotapi.C
 	)
 	keyboard := BuildMainMenu()
 	msg.ReplyMarkup = &keyboard
-	b.api.Send(msg)
+	b.send(msg)
 }
 
 // handleSelectThresholdCallback processes threshold selection from buttons
 func (b *Bot) handleSelectThresholdCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	// Extract threshold value from callback data (e.g., "select_threshold_5" -> "5")
+	// Extract threshold value from callback data (e.g., "select_threshold_5" -> "5%", "select_cents_5" -> "5¢", "select_sigma_3" -> "3σ")
 	parts := strings.Split(callback.Data, "_")
 	if len(parts) < 3 {
 		b.log.Errorf("Invalid threshold callback data: %s", callback.Data)
@@ -267,28 +458,189 @@ func (b *Bot) handleSelectThresholdCallback(ctx context.Context, callback *tgbot
 	state := b.getUserState(callback.From.ID)
 	if state.MarketID == "" {
 		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
-		b.api.Send(msg)
+		b.send(msg)
 		return
 	}
 
+	switch {
+	case strings.HasPrefix(callback.Data, CallbackSelectCents+"_"):
+		state.Data["threshold_unit"] = storage.ThresholdUnitCents
+	case strings.HasPrefix(callback.Data, CallbackSelectSigma+"_"):
+		state.Data["threshold_unit"] = storage.ThresholdUnitSigma
+	default:
+		state.Data["threshold_unit"] = storage.ThresholdUnitPercent
+	}
+
 	// Delete the threshold selection message
 	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	b.api.Send(deleteMsg)
+	b.send(deleteMsg)
+
+	if alertID, ok := editingAlertID(state); ok {
+		b.applyAlertEdit(ctx, callback.Message.Chat.ID, callback.From.ID, alertID, setThreshold(thresholdUnit(state), threshold))
+		return
+	}
+
+	// Ask for the spike direction
+	b.promptDirection(callback.Message.Chat.ID, state, threshold)
+}
+
+// handleSelectDirectionCallback processes the direction of a spike alert and asks for the window
+func (b *Bot) handleSelectDirectionCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract direction from callback data (e.g., "select_direction_up")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid direction callback data: %s", callback.Data)
+		return
+	}
+
+	direction := parts[2]
+	if direction != storage.DirectionUp && direction != storage.DirectionDown && direction != storage.DirectionBoth {
+		b.log.Errorf("Unknown direction in callback: %s", direction)
+		return
+	}
+
+	// Get user state
+	state := b.getUserState(callback.From.ID)
+	if alertID, ok := editingAlertID(state); ok {
+		b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+		b.applyAlertEdit(ctx, callback.Message.Chat.ID, callback.From.ID, alertID, func(alert *storage.Alert) {
+			alert.Direction = direction
+		})
+		return
+	}
+	if state.MarketID == "" || state.Threshold == 0 {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+	state.Data["direction"] = direction
+	state.Step = "awaiting_window"
+
+	// Ask for the lookback window
+	msg := tgbotapi.NewEditMessageText(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		MsgWindowPrompt,
+	)
+	keyboard := BuildWindowSelectionMenu()
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleSelectWindowCallback processes lookback window selection and creates the alert
+func (b *Bot) handleSelectWindowCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract window seconds from callback data (e.g., "select_window_300" -> 300)
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid window callback data: %s", callback.Data)
+		return
+	}
+
+	windowSeconds, err := strconv.Atoi(parts[2])
+	if err != nil || windowSeconds <= 0 {
+		b.log.Errorf("Failed to parse window: %s", parts[2])
+		return
+	}
+
+	// Get user state
+	state := b.getUserState(callback.From.ID)
+	if alertID, ok := editingAlertID(state); ok {
+		b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+		b.applyAlertEdit(ctx, callback.Message.Chat.ID, callback.From.ID, alertID, func(alert *storage.Alert) {
+			alert.WindowSeconds = windowSeconds
+		})
+		return
+	}
+	if state.MarketID == "" || !hasCondition(state) {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+	state.Data["window_seconds"] = windowSeconds
+	state.Step = "awaiting_cooldown"
+
+	// Ask for the notification cooldown
+	msg := tgbotapi.NewEditMessageText(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		MsgCooldownPrompt,
+	)
+	keyboard := BuildCooldownSelectionMenu(b.defaultCooldown)
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleSelectCooldownCallback processes cooldown selection and creates the alert
+func (b *Bot) handleSelectCooldownCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract cooldown from callback data (e.g., "select_cooldown_3600" or "select_cooldown_default")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid cooldown callback data: %s", callback.Data)
+		return
+	}
+
+	// Get user state
+	state := b.getUserState(callback.From.ID)
+	if state.MarketID == "" || !hasCondition(state) {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	// The default option leaves the cooldown unset so later changes to the global default apply
+	delete(state.Data, "cooldown_seconds")
+	if parts[2] != CooldownDefault {
+		cooldownSeconds, err := strconv.Atoi(parts[2])
+		if err != nil || cooldownSeconds <= 0 {
+			b.log.Errorf("Failed to parse cooldown: %s", parts[2])
+			return
+		}
+		state.Data["cooldown_seconds"] = cooldownSeconds
+	}
+
+	// Delete the cooldown selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
 
 	// Create the alert
-	b.createAlert(ctx, callback.Message.Chat.ID, callback.From.ID, state, threshold)
+	b.createAlert(ctx, callback.Message.Chat.ID, callback.From.ID, state)
+}
+
+// handleThresholdUnitCallback switches the threshold menu between percent, cents and standard deviations
+func (b *Bot) handleThresholdUnitCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract unit from callback data (e.g., "threshold_unit_cents")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 || (parts[2] != storage.ThresholdUnitPercent && parts[2] != storage.ThresholdUnitCents && parts[2] != storage.ThresholdUnitSigma) {
+		b.log.Errorf("Invalid threshold unit callback data: %s", callback.Data)
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	state.Data["threshold_unit"] = parts[2]
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, thresholdPrompt(parts[2]))
+	keyboard := BuildThresholdSelectionMenu(parts[2])
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
 }
 
 // handleCustomThresholdCallback prompts user to enter custom threshold
 func (b *Bot) handleCustomThresholdCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
 	// Delete the threshold selection message
 	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	b.api.Send(deleteMsg)
-
-	// Prompt for manual input
-	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please enter your custom threshold percentage (e.g., 15 for ±15%):")
+	b.send(deleteMsg)
+
+	// Prompt for manual input in the unit currently shown in the menu
+	prompt := "Please enter your custom threshold percentage (e.g., 15 for ±15%):"
+	switch thresholdUnit(b.getUserState(callback.From.ID)) {
+	case storage.ThresholdUnitCents:
+		prompt = "Please enter your custom threshold in cents (e.g., 4 for ±$0.04):"
+	case storage.ThresholdUnitSigma:
+		prompt = "Please enter your custom threshold in standard deviations (e.g., 3.5 for ±3.5σ):"
+	}
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, prompt)
 	msg.ParseMode = "HTML"
-	b.api.Send(msg)
+	b.send(msg)
 
 	// User state is already set to "awaiting_threshold", so manual input will be handled
 }
@@ -0,0 +1,53 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleSelectBandCallback processes the band of a consistency alert and asks for the cooldown
+func (b *Bot) handleSelectBandCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract cents from callback data (e.g., "select_band_5")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid band callback data: %s", callback.Data)
+		return
+	}
+
+	cents, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || cents <= 0 {
+		b.log.Errorf("Failed to parse band: %s", parts[2])
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state.MarketID == "" {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	// Delete the band selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
+
+	state.Data["band_cents"] = cents
+	b.promptCooldown(callback.Message.Chat.ID, state, fmt.Sprintf("<b>Band:</b> ±%g¢ around $1", cents))
+}
+
+// handleBandInput processes a custom consistency band in cents
+func (b *Bot) handleBandInput(ctx context.Context, message *tgbotapi.Message) {
+	cents, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(message.Text), "¢"), 64)
+	if err != nil || cents < 0.5 || cents > 50 {
+		b.SendMessage(message.Chat.ID, MsgInvalidBand, nil)
+		return
+	}
+
+	state := b.getUserState(message.From.ID)
+	state.Data["band_cents"] = cents
+	b.promptCooldown(message.Chat.ID, state, fmt.Sprintf("<b>Band:</b> ±%g¢ around $1", cents))
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qmitry/opinion-alert-bot/internal/api"
 	"github.com/qmitry/opinion-alert-bot/internal/storage"
 )
 
@@ -37,6 +38,20 @@ func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 		b.handleMarketIDInput(ctx, message)
 	case "awaiting_threshold":
 		b.handleThresholdInput(ctx, message)
+	case "awaiting_level":
+		b.handleLevelInput(ctx, message)
+	case "awaiting_volume_multiple":
+		b.handleMultipleInput(ctx, message)
+	case "awaiting_spread":
+		b.handleSpreadInput(ctx, message)
+	case "awaiting_liquidity":
+		b.handleLiquidityInput(ctx, message)
+	case "awaiting_band":
+		b.handleBandInput(ctx, message)
+	case "awaiting_timezone":
+		b.handleTimezoneInput(ctx, message)
+	case "awaiting_quiet_hours":
+		b.handleQuietHoursInput(ctx, message)
 	default:
 		// No active conversation, show unknown command message
 		b.SendMessage(message.Chat.ID, MsgUnknownCommand, BuildMainMenu())
@@ -54,6 +69,12 @@ func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
 		b.handleCreateCommand(ctx, message)
 	case "alerts":
 		b.handleAlertsCommand(ctx, message)
+	case "settings":
+		b.clearUserState(message.From.ID)
+		b.showSettings(ctx, message.Chat.ID, 0, message.From.ID, "")
+	case "history":
+		b.clearUserState(message.From.ID)
+		b.showAlertHistory(ctx, message.Chat.ID, 0, message.From.ID, 0, 0)
 	default:
 		b.SendMessage(message.Chat.ID, MsgUnknownCommand, BuildMainMenu())
 	}
@@ -110,7 +131,6 @@ func (b *Bot) handleMarketIDInput(ctx context.Context, message *tgbotapi.Message
 	}
 
 	// Validate market exists by fetching details
-	// For multi-outcome markets, this will automatically select the first outcome token
 	marketDetails, err := b.apiClient.GetMarketDetails(ctx, marketID)
 	if err != nil {
 		b.log.Warnf("Market %s not found: %v", marketID, err)
@@ -119,35 +139,150 @@ func (b *Bot) handleMarketIDInput(ctx context.Context, message *tgbotapi.Message
 		return
 	}
 
-	// Store market ID, name, and token ID (automatically set for both binary and multi-outcome)
-	state := b.getUserState(message.From.ID)
+	b.startAlertSetup(ctx, message.Chat.ID, message.From.ID, marketID, marketDetails,
+		fmt.Sprintf("✅ Market found: <b>%s</b>", marketDetails.MarketTitle))
+}
+
+// startAlertSetup stores the validated market in the user's state and asks for the
+// outcome to watch (multi-outcome markets) or directly for the threshold (binary markets)
+func (b *Bot) startAlertSetup(ctx context.Context, chatID, userID int64, marketID string, marketDetails *api.MarketDetail, header string) {
+	state := b.getUserState(userID)
 	state.MarketID = marketID
 	state.Data["market_name"] = marketDetails.MarketTitle
-	state.Data["token_id"] = marketDetails.YesTokenID
-	state.Step = "awaiting_threshold"
 
-	// Confirm market and ask for threshold with quick-select buttons
-	confirmMsg := fmt.Sprintf("✅ Market found: <b>%s</b>\n\n%s",
-		marketDetails.MarketTitle, MsgThresholdPrompt)
-	b.SendMessage(message.Chat.ID, confirmMsg, BuildThresholdSelectionMenu())
+	if !marketDetails.IsCategorical() {
+		// Binary market - watch the YES token
+		state.Data["outcomes"] = []OutcomeOption{{TokenID: marketDetails.YesTokenID}}
+		state.Step = "awaiting_type"
+		b.SendMessage(chatID, fmt.Sprintf("%s\n\n%s", header, MsgAlertTypePrompt), BuildAlertTypeSelectionMenu())
+		return
+	}
+
+	// Multi-outcome market - list every child market with its current YES price
+	outcomes := make([]OutcomeOption, 0, len(marketDetails.ChildMarkets))
+	for _, child := range marketDetails.ChildMarkets {
+		outcome := OutcomeOption{
+			TokenID: child.YesTokenID,
+			Name:    child.MarketTitle,
+		}
+
+		tokenPrice, err := b.apiClient.GetTokenPrice(ctx, child.YesTokenID)
+		if err != nil {
+			b.log.Warnf("Failed to get price for outcome %s of market %s: %v", child.MarketTitle, marketID, err)
+		} else if price, err := api.ParseTokenPrice(tokenPrice.Price); err == nil {
+			outcome.Price = price
+		}
+
+		outcomes = append(outcomes, outcome)
+	}
+
+	state.Data["available_outcomes"] = outcomes
+	state.Step = "awaiting_outcome"
+	b.SendMessage(chatID, fmt.Sprintf("%s\n\n%s", header, MsgOutcomePrompt), BuildOutcomeSelectionMenu(outcomes))
 }
 
 // handleThresholdInput processes threshold percentage input
 func (b *Bot) handleThresholdInput(ctx context.Context, message *tgbotapi.Message) {
 	thresholdStr := strings.TrimSpace(message.Text)
 
+	state := b.getUserState(message.From.ID)
+	unit := thresholdUnit(state)
+
 	threshold, err := strconv.ParseFloat(thresholdStr, 64)
-	if err != nil || threshold < 1 || threshold > 100 {
-		b.SendMessage(message.Chat.ID, MsgInvalidThreshold, nil)
+	switch unit {
+	case storage.ThresholdUnitCents:
+		if err != nil || threshold < 0.1 || threshold > 99 {
+			b.SendMessage(message.Chat.ID, MsgInvalidCents, nil)
+			return
+		}
+	case storage.ThresholdUnitSigma:
+		if err != nil || threshold < 1 || threshold > 10 {
+			b.SendMessage(message.Chat.ID, MsgInvalidSigma, nil)
+			return
+		}
+	default:
+		if err != nil || threshold < 1 || threshold > 100 {
+			b.SendMessage(message.Chat.ID, MsgInvalidThreshold, nil)
+			return
+		}
+	}
+
+	if alertID, ok := editingAlertID(state); ok {
+		b.applyAlertEdit(ctx, message.Chat.ID, message.From.ID, alertID, setThreshold(unit, threshold))
+		return
+	}
+
+	b.promptDirection(message.Chat.ID, state, threshold)
+}
+
+// handleLevelInput processes the price level of a crossing alert
+func (b *Bot) handleLevelInput(ctx context.Context, message *tgbotapi.Message) {
+	levelStr := strings.TrimSpace(message.Text)
+
+	level, err := strconv.ParseFloat(levelStr, 64)
+	if err != nil || level < 0.01 || level > 0.99 {
+		b.SendMessage(message.Chat.ID, MsgInvalidLevel, nil)
 		return
 	}
 
 	state := b.getUserState(message.From.ID)
-	b.createAlert(ctx, message.Chat.ID, message.From.ID, state, threshold)
+	state.Data["level_price"] = level
+	state.Step = "awaiting_level_direction"
+	b.SendMessage(message.Chat.ID, MsgLevelDirection, BuildLevelDirectionMenu())
+}
+
+// promptDirection stores the chosen threshold and asks for the direction of the spike
+func (b *Bot) promptDirection(chatID int64, state *UserState, threshold float64) {
+	state.Threshold = threshold
+	state.Step = "awaiting_direction"
+	b.SendMessage(chatID, fmt.Sprintf("<b>Threshold:</b> %s\n\n%s", FormatThresholdValue(thresholdUnit(state), threshold), MsgDirectionPrompt), BuildDirectionSelectionMenu())
+}
+
+// thresholdUnit returns the spike threshold unit chosen in the user's state (percent by default)
+func thresholdUnit(state *UserState) string {
+	if unit, _ := state.Data["threshold_unit"].(string); unit == storage.ThresholdUnitCents || unit == storage.ThresholdUnitSigma {
+		return unit
+	}
+	return storage.ThresholdUnitPercent
+}
+
+// hasCondition reports whether the user's state holds the trigger condition of the alert being created:
+// a threshold for spike alerts, the surge multiple and trade value for volume alerts, the limit
+// of an order book alert, or the band of a consistency alert
+func hasCondition(state *UserState) bool {
+	alertType, _ := state.Data["alert_type"].(string)
+	switch alertType {
+	case storage.AlertTypeVolume:
+		_, ok := state.Data["print_notional"].(float64)
+		return ok
+	case storage.AlertTypeSpread:
+		_, ok := state.Data["spread_cents"].(float64)
+		return ok
+	case storage.AlertTypeLiquidity:
+		_, ok := state.Data["min_liquidity"].(float64)
+		return ok
+	case storage.AlertTypeConsistency:
+		_, ok := state.Data["band_cents"].(float64)
+		return ok
+	default:
+		return state.Threshold != 0
+	}
 }
 
-// createAlert is a helper function to create an alert with the given threshold
-func (b *Bot) createAlert(ctx context.Context, chatID int64, userID int64, state *UserState, threshold float64) {
+// thresholdPrompt returns the threshold prompt for the given unit
+func thresholdPrompt(unit string) string {
+	switch unit {
+	case storage.ThresholdUnitCents:
+		return MsgThresholdCents
+	case storage.ThresholdUnitSigma:
+		return MsgThresholdSigma
+	default:
+		return MsgThresholdPrompt
+	}
+}
+
+// createAlert is a helper function to create an alert from the settings collected in the user's state
+func (b *Bot) createAlert(ctx context.Context, chatID int64, userID int64, state *UserState) {
 	// Get user from database
 	user, err := b.storage.GetUserByTelegramID(ctx, userID)
 	if err != nil {
@@ -157,34 +292,136 @@ func (b *Bot) createAlert(ctx context.Context, chatID int64, userID int64, state
 		return
 	}
 
-	// Get market name and token ID from state (was saved during market ID validation)
+	// Get market name and selected outcomes from state (saved during market selection)
 	marketName, _ := state.Data["market_name"].(string)
 	if marketName == "" {
 		marketName = "Market " + state.MarketID
 	}
 
-	var tokenID *string
-	if tokenIDStr, ok := state.Data["token_id"].(string); ok && tokenIDStr != "" {
-		tokenID = &tokenIDStr
+	outcomes, _ := state.Data["outcomes"].([]OutcomeOption)
+	if len(outcomes) == 0 {
+		b.log.Errorf("No outcome selected for market %s", state.MarketID)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		b.clearUserState(userID)
+		return
+	}
+
+	windowSeconds, _ := state.Data["window_seconds"].(int)
+	if windowSeconds <= 0 {
+		windowSeconds = storage.DefaultWindowSeconds
 	}
 
-	// Create the alert
-	_, err = b.storage.CreateAlert(ctx, user.ID, state.MarketID, marketName, tokenID, threshold)
-	if err != nil {
-		if strings.Contains(err.Error(), "cannot track more than") {
-			b.SendMessage(chatID, MsgMaxMarketsReached, BuildMainMenu())
-		} else {
-			b.log.Errorf("Failed to create alert: %v", err)
-			b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+	var cooldownSeconds *int
+	if seconds, ok := state.Data["cooldown_seconds"].(int); ok {
+		cooldownSeconds = &seconds
+	}
+
+	// Settings shared by the alerts of every selected outcome
+	settings := storage.Alert{
+		UserID:          user.ID,
+		MarketID:        state.MarketID,
+		MarketName:      marketName,
+		AlertType:       storage.AlertTypeSpike,
+		ThresholdUnit:   thresholdUnit(state),
+		Direction:       storage.DirectionBoth,
+		WindowSeconds:   windowSeconds,
+		CooldownSeconds: cooldownSeconds,
+	}
+	switch settings.ThresholdUnit {
+	case storage.ThresholdUnitCents:
+		settings.ThresholdCents = state.Threshold
+	case storage.ThresholdUnitSigma:
+		settings.ThresholdSigma = state.Threshold
+	default:
+		settings.ThresholdPct = state.Threshold
+	}
+	if direction, ok := state.Data["direction"].(string); ok {
+		settings.Direction = direction
+	}
+
+	// Alerts other than spike alerts replace the threshold with a condition of their own
+	alertType, _ := state.Data["alert_type"].(string)
+	switch alertType {
+	case storage.AlertTypeCrossing:
+		level, _ := state.Data["level_price"].(float64)
+		settings.LevelPrice = &level
+		settings.LevelDirection, _ = state.Data["level_direction"].(string)
+	case storage.AlertTypeVolume:
+		settings.VolumeMultiple, _ = state.Data["volume_multiple"].(float64)
+		settings.PrintNotional, _ = state.Data["print_notional"].(float64)
+	case storage.AlertTypeSpread, storage.AlertTypeLiquidity:
+		settings.SpreadCents, _ = state.Data["spread_cents"].(float64)
+		settings.MinLiquidity, _ = state.Data["min_liquidity"].(float64)
+	case storage.AlertTypeConsistency:
+		settings.BandCents, _ = state.Data["band_cents"].(float64)
+
+		// Consistency alerts compare all outcomes of the market, so one alert covers the whole market
+		outcomes = []OutcomeOption{{}}
+	default:
+		alertType = storage.AlertTypeSpike
+	}
+	if alertType != storage.AlertTypeSpike {
+		settings.AlertType = alertType
+		settings.ThresholdPct = 0
+		settings.ThresholdCents = 0
+		settings.ThresholdSigma = 0
+	}
+
+	// Create one alert per selected outcome
+	var created []string
+	for _, outcome := range outcomes {
+		params := settings
+		params.OutcomeName = outcome.Name
+		if outcome.TokenID != "" {
+			tokenIDStr := outcome.TokenID
+			params.TokenID = &tokenIDStr
+		}
+
+		_, err = b.storage.CreateAlert(ctx, &params)
+		if err != nil {
+			if strings.Contains(err.Error(), "cannot track more than") {
+				b.SendMessage(chatID, MsgMaxMarketsReached, BuildMainMenu())
+			} else if strings.Contains(err.Error(), "has resolved") {
+				b.SendMessage(chatID, MsgMarketResolved, BuildMainMenu())
+			} else {
+				b.log.Errorf("Failed to create alert: %v", err)
+				b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+			}
+			b.clearUserState(userID)
+			return
+		}
+
+		if outcome.Name != "" {
+			created = append(created, outcome.Name)
 		}
-		b.clearUserState(userID)
-		return
 	}
 
-	// Success - show market name
-	successMsg := fmt.Sprintf("✅ Alert created successfully!\n\n<b>Market:</b> %s\n<b>Threshold:</b> ±%.1f%%\n\nYou'll be notified when the price changes by this amount.",
-		marketName, threshold)
+	// Success - show market name and watched outcomes
+	outcomeLine := ""
+	if len(created) > 0 {
+		outcomeLine = fmt.Sprintf("\n<b>Outcomes:</b> %s", strings.Join(created, ", "))
+	}
+	var settingsLines string
+	switch settings.AlertType {
+	case storage.AlertTypeCrossing:
+		settingsLines = fmt.Sprintf("<b>Level:</b> %s $%.4f\n\nYou'll be notified when the price crosses this level.",
+			settings.LevelDirection, *settings.LevelPrice)
+	case storage.AlertTypeVolume:
+		settingsLines = fmt.Sprintf("<b>Condition:</b> %s\n<b>Cooldown:</b> %s\n\nYou'll be notified when trading picks up this much.",
+			FormatVolumeCondition(settings.VolumeMultiple, settings.PrintNotional, settings.Window()), FormatDuration(settings.Cooldown(b.defaultCooldown)))
+	case storage.AlertTypeSpread, storage.AlertTypeLiquidity:
+		settingsLines = fmt.Sprintf("<b>Condition:</b> %s\n<b>Cooldown:</b> %s\n\nYou'll be notified when the order book gets this wide or thin.",
+			FormatBookCondition(settings.AlertType, settings.SpreadCents, settings.MinLiquidity), FormatDuration(settings.Cooldown(b.defaultCooldown)))
+	case storage.AlertTypeConsistency:
+		settingsLines = fmt.Sprintf("<b>Condition:</b> %s\n<b>Cooldown:</b> %s\n\nYou'll be notified when the outcome prices stop adding up, with the legs that are off.",
+			FormatConsistencyCondition(settings.BandCents), FormatDuration(settings.Cooldown(b.defaultCooldown)))
+	default:
+		cooldown := settings.Cooldown(b.defaultCooldown)
+		settingsLines = fmt.Sprintf("<b>Threshold:</b> %s\n<b>Direction:</b> %s\n<b>Window:</b> %s\n<b>Cooldown:</b> %s\n\nYou'll be notified when the price changes by this amount within the window.",
+			FormatThreshold(settings.Direction, settings.ThresholdUnit, settings.ThresholdValue()), DirectionLabel(settings.Direction), FormatDuration(settings.Window()), FormatDuration(cooldown))
+	}
+	successMsg := fmt.Sprintf("✅ Alert created successfully!\n\n<b>Market:</b> %s%s\n%s",
+		marketName, outcomeLine, settingsLines)
 	b.SendMessage(chatID, successMsg, BuildMainMenu())
 	b.clearUserState(userID)
 }
-
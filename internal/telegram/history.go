@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// handleHistoryCallback shows a page of alert history (format: "history_<alertID>_<page>", alertID 0 for all alerts)
+func (b *Bot) handleHistoryCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid history callback data: %s", callback.Data)
+		return
+	}
+
+	alertID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		b.log.Errorf("Invalid alert ID in callback: %s", parts[1])
+		return
+	}
+
+	page, err := strconv.Atoi(parts[2])
+	if err != nil || page < 0 {
+		b.log.Errorf("Invalid history page in callback: %s", parts[2])
+		return
+	}
+
+	b.showAlertHistory(ctx, callback.Message.Chat.ID, callback.Message.MessageID, callback.From.ID, alertID, page)
+}
+
+// showAlertHistory displays a page of triggered alerts for one alert, or for all of the user's alerts
+// when alertID is 0. The page replaces messageID, or is sent as a new message when messageID is 0
+func (b *Bot) showAlertHistory(ctx context.Context, chatID int64, messageID int, telegramID, alertID int64, page int) {
+	user, err := b.storage.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		b.log.Errorf("Failed to get user: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	alerts, err := b.storage.GetAlertsByUserID(ctx, user.ID)
+	if err != nil {
+		b.log.Errorf("Failed to get alerts: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	labels := make(map[int64]string, len(alerts))
+	for _, alert := range alerts {
+		labels[alert.ID] = AlertLabel(&alert)
+	}
+
+	title := "Alert History"
+	if alertID != 0 {
+		label, ok := labels[alertID]
+		if !ok {
+			b.SendMessage(chatID, MsgAlertNotFound, BuildMainMenu())
+			return
+		}
+		title = "History: " + label
+	}
+
+	now := time.Now()
+	count24h, err := b.storage.CountAlertHistory(ctx, user.ID, alertID, now.Add(-24*time.Hour), false)
+	if err != nil {
+		b.log.Errorf("Failed to count alert history: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+	count7d, err := b.storage.CountAlertHistory(ctx, user.ID, alertID, now.Add(-7*24*time.Hour), false)
+	if err != nil {
+		b.log.Errorf("Failed to count alert history: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+	// Pages also list the triggers suppressed by noise filters
+	total, err := b.storage.CountAlertHistory(ctx, user.ID, alertID, time.Time{}, true)
+	if err != nil {
+		b.log.Errorf("Failed to count alert history: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	totalPages := (total + HistoryPageSize - 1) / HistoryPageSize
+	if page >= totalPages && totalPages > 0 {
+		page = totalPages - 1
+	}
+
+	var history []storage.AlertHistory
+	if alertID != 0 {
+		history, err = b.storage.GetAlertHistoryByAlertID(ctx, alertID, HistoryPageSize, page*HistoryPageSize)
+	} else {
+		history, err = b.storage.GetAlertHistoryByUserID(ctx, user.ID, HistoryPageSize, page*HistoryPageSize)
+	}
+	if err != nil {
+		b.log.Errorf("Failed to get alert history: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	entries := make([]HistoryEntry, 0, len(history))
+	for _, h := range history {
+		entry := HistoryEntry{
+			TriggeredAt:   h.TriggeredAt.In(user.Location()),
+			PreviousPrice: h.PreviousPrice,
+			CurrentPrice:  h.CurrentPrice,
+			ChangePct:     h.ChangePct,
+		}
+		if h.IsSuppressed() {
+			entry.SuppressedBy = h.SuppressedBy
+		}
+		if alertID == 0 {
+			entry.Label = labels[h.AlertID]
+		}
+		entries = append(entries, entry)
+	}
+
+	text := FormatAlertHistory(title, entries, count24h, count7d, page, totalPages)
+	keyboard := BuildHistoryMenu(alertID, page, totalPages)
+
+	if messageID == 0 {
+		b.SendMessage(chatID, text, keyboard)
+		return
+	}
+
+	msg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// AlertLabel returns a short name for an alert (e.g., "Yes: Market #123")
+func AlertLabel(alert *storage.Alert) string {
+	name := alert.MarketName
+	if name == "" {
+		name = "Market #" + alert.MarketID
+	}
+	if alert.OutcomeName != "" {
+		name = fmt.Sprintf("%s: %s", alert.OutcomeName, name)
+	}
+	return name
+}
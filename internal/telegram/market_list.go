@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"context"
+	"time"
 )
 
 // showMyMarkets displays the markets being tracked by the user
@@ -22,6 +23,13 @@ func (b *Bot) showMyMarkets(ctx context.Context, chatID, userID int64) {
 		return
 	}
 
+	storedMarkets, err := b.storage.GetMarketsByUserID(ctx, user.ID)
+	if err != nil {
+		b.log.Errorf("Failed to get markets: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
 	// Extract unique markets with names
 	marketMap := make(map[string]string) // marketID -> marketName
 	for _, alert := range alerts {
@@ -35,11 +43,16 @@ func (b *Bot) showMyMarkets(ctx context.Context, chatID, userID int64) {
 
 	// Build MarketInfo slice
 	var markets []MarketInfo
+	now := time.Now()
 	for marketID, marketName := range marketMap {
-		markets = append(markets, MarketInfo{
+		info := MarketInfo{
 			MarketID:   marketID,
 			MarketName: marketName,
-		})
+		}
+		if market, ok := storedMarkets[marketID]; ok {
+			info.MarketStatus = market.StatusLabel(now)
+		}
+		markets = append(markets, info)
 	}
 
 	// Format and send the message
@@ -2,8 +2,10 @@ package telegram
 
 import (
 	"fmt"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
 )
 
 // Callback data constants
@@ -19,8 +21,115 @@ const (
 	CallbackCustomMarket    = "custom_market"
 	CallbackSelectThreshold = "select_threshold"
 	CallbackCustomThreshold = "custom_threshold"
+	CallbackSelectOutcome   = "select_outcome"
+	CallbackSelectWindow    = "select_window"
+	CallbackSelectCooldown  = "select_cooldown"
+	CallbackSelectType      = "select_type"
+	CallbackSelectLevel     = "select_level"
+	CallbackSelectDirection = "select_direction"
+	CallbackSelectCents     = "select_cents"
+	CallbackSelectSigma     = "select_sigma"
+	CallbackSelectMultiple  = "select_multiple"
+	CallbackSelectTrade     = "select_trade"
+	CallbackSelectSpread    = "select_spread"
+	CallbackSelectLiquidity = "select_liquidity"
+	CallbackSelectBand      = "select_band"
+	CallbackThresholdUnit   = "threshold_unit"
+	CallbackEditAlert       = "edit_alert"
+	CallbackEditThreshold   = "edit_threshold"
+	CallbackEditDirection   = "edit_direction"
+	CallbackEditWindow      = "edit_window"
+	CallbackEditFilters     = "edit_filters"
+	CallbackFilterSize      = "filter_size"
+	CallbackFilterPolls     = "filter_polls"
+	CallbackPauseAlert      = "pause_alert"
+	CallbackResumeAlert     = "resume_alert"
+	CallbackPauseAll        = "pause_all"
+	CallbackHistory         = "history"
+	CallbackSnooze          = "snooze"
+	CallbackRaiseThreshold  = "raise_threshold"
+	CallbackMuteTomorrow    = "mute_tomorrow"
+	CallbackAlertHistory    = "alert_history"
+	CallbackUnmuteAlert     = "unmute_alert"
+	CallbackSettings        = "settings"
+	CallbackSetTimezone     = "set_timezone"
+	CallbackQuietHours      = "quiet_hours"
+	CallbackUrgentThreshold = "urgent_threshold"
+	CallbackDigestMode      = "digest_mode"
+	CallbackCutoffReminders = "cutoff_reminders"
 )
 
+// Suffixes of the quiet_hours and urgent_threshold callbacks
+const (
+	SettingOff    = "off"
+	SettingCustom = "custom"
+	SettingMenu   = "menu"
+)
+
+// QuietHoursPresets are the quiet hours windows offered in the settings, as start and end hours
+var QuietHoursPresets = [][2]int{
+	{22, 7},
+	{23, 8},
+	{0, 8},
+}
+
+// UrgentThresholds are the urgent thresholds offered in the settings, in percent
+var UrgentThresholds = []int{10, 25, 50}
+
+// ReminderOffsets are the cutoff reminder options offered in the settings
+var ReminderOffsets = []time.Duration{
+	24 * time.Hour,
+	6 * time.Hour,
+	time.Hour,
+	10 * time.Minute,
+}
+
+// SnoozeDurations are the snooze options offered on alert notifications
+var SnoozeDurations = []time.Duration{
+	15 * time.Minute,
+	time.Hour,
+	24 * time.Hour,
+}
+
+// HistoryPageSize is the number of alert history entries shown per page
+const HistoryPageSize = 10
+
+// CooldownDefault is the select_cooldown suffix for using the global default cooldown
+const CooldownDefault = "default"
+
+// AlertWindows are the lookback windows offered when creating an alert
+var AlertWindows = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	24 * time.Hour,
+}
+
+// VolumeMultiples are the volume surge multiples offered when creating a volume alert
+var VolumeMultiples = []int{2, 3, 5, 10}
+
+// TradeValues are the large trade values offered when creating a volume alert, in dollars
+var TradeValues = []int{1000, 5000, 10000, 50000}
+
+// SpreadLimits are the spreads offered when creating a spread alert, in cents
+var SpreadLimits = []int{2, 3, 5, 10}
+
+// LiquidityLimits are the top-of-book values offered when creating a liquidity alert, in dollars
+var LiquidityLimits = []int{100, 500, 1000, 5000}
+
+// ConsistencyBands are the bands around $1 offered when creating a consistency alert, in cents
+var ConsistencyBands = []int{2, 3, 5, 10}
+
+// MinTradeSizes are the minimum trade sizes offered as a noise filter, in shares
+var MinTradeSizes = []int{10, 100, 1000}
+
+// ConfirmPolls are the confirmation poll counts offered as a noise filter
+var ConfirmPolls = []int{1, 2, 3, 5}
+
+// OutcomeAll is the select_outcome suffix for watching every outcome of a market
+const OutcomeAll = "all"
+
 // BuildMainMenu creates the main menu inline keyboard
 func BuildMainMenu() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
@@ -32,12 +141,120 @@ func BuildMainMenu() tgbotapi.InlineKeyboardMarkup {
 			tgbotapi.NewInlineKeyboardButtonData("My Markets", CallbackMyMarkets),
 			tgbotapi.NewInlineKeyboardButtonData("Help", CallbackHelp),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Settings", CallbackSettings),
+		),
+	)
+}
+
+// BuildSettingsMenu creates the user settings menu
+func BuildSettingsMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🌍 Timezone", CallbackSetTimezone),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🌙 Quiet Hours", fmt.Sprintf("%s_%s", CallbackQuietHours, SettingMenu)),
+			tgbotapi.NewInlineKeyboardButtonData("🚨 Urgent Threshold", fmt.Sprintf("%s_%s", CallbackUrgentThreshold, SettingMenu)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📰 Digest", fmt.Sprintf("%s_%s", CallbackDigestMode, SettingMenu)),
+			tgbotapi.NewInlineKeyboardButtonData("⏰ Cutoff Reminders", fmt.Sprintf("%s_%s", CallbackCutoffReminders, SettingMenu)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildQuietHoursMenu creates a menu with common quiet hours windows
+func BuildQuietHoursMenu() tgbotapi.InlineKeyboardMarkup {
+	var presets []tgbotapi.InlineKeyboardButton
+	for _, preset := range QuietHoursPresets {
+		presets = append(presets, tgbotapi.NewInlineKeyboardButtonData(
+			FormatQuietHours(preset[0], preset[1]),
+			fmt.Sprintf("%s_%d-%d", CallbackQuietHours, preset[0], preset[1]),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		presets,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Custom", fmt.Sprintf("%s_%s", CallbackQuietHours, SettingCustom)),
+			tgbotapi.NewInlineKeyboardButtonData("Off", fmt.Sprintf("%s_%s", CallbackQuietHours, SettingOff)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Settings", CallbackSettings),
+		),
+	)
+}
+
+// BuildCutoffRemindersMenu creates the cutoff reminder toggles, marking the enabled offsets
+func BuildCutoffRemindersMenu(enabled []time.Duration) tgbotapi.InlineKeyboardMarkup {
+	var toggles []tgbotapi.InlineKeyboardButton
+	for _, offset := range ReminderOffsets {
+		label := FormatDuration(offset)
+		for _, e := range enabled {
+			if e == offset {
+				label = "✅ " + label
+				break
+			}
+		}
+		toggles = append(toggles, tgbotapi.NewInlineKeyboardButtonData(
+			label,
+			fmt.Sprintf("%s_%d", CallbackCutoffReminders, int(offset.Seconds())),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		toggles,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Settings", CallbackSettings),
+		),
+	)
+}
+
+// BuildDigestModeMenu creates the digest mode menu
+func BuildDigestModeMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Hourly", fmt.Sprintf("%s_%s", CallbackDigestMode, storage.DigestHourly)),
+			tgbotapi.NewInlineKeyboardButtonData("Daily", fmt.Sprintf("%s_%s", CallbackDigestMode, storage.DigestDaily)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Off (real-time alerts)", fmt.Sprintf("%s_%s", CallbackDigestMode, storage.DigestOff)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Settings", CallbackSettings),
+		),
+	)
+}
+
+// BuildUrgentThresholdMenu creates a menu with the urgent thresholds
+func BuildUrgentThresholdMenu() tgbotapi.InlineKeyboardMarkup {
+	var presets []tgbotapi.InlineKeyboardButton
+	for _, threshold := range UrgentThresholds {
+		presets = append(presets, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("±%d%%", threshold),
+			fmt.Sprintf("%s_%d", CallbackUrgentThreshold, threshold),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		presets,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Off (always wait)", fmt.Sprintf("%s_%s", CallbackUrgentThreshold, SettingOff)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Settings", CallbackSettings),
+		),
 	)
 }
 
-// BuildAlertListMenu creates the alert list menu with delete buttons
+// BuildAlertListMenu creates the alert list menu with delete, edit and pause/resume buttons
 func BuildAlertListMenu(alerts map[string][]AlertInfo) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
+	hasActive := false
 
 	for marketID, alertList := range alerts {
 		// Market header (not clickable)
@@ -50,19 +267,56 @@ func BuildAlertListMenu(alerts map[string][]AlertInfo) tgbotapi.InlineKeyboardMa
 				displayName = fmt.Sprintf("%s #%s", displayName, marketID)
 			}
 
+			// Lead with the outcome so alerts on the same market stay distinguishable after truncation
+			if alert.OutcomeName != "" {
+				displayName = fmt.Sprintf("%s: %s", alert.OutcomeName, displayName)
+			}
+
 			// Truncate if too long for button (max 64 chars for Telegram)
 			if len(displayName) > 35 {
 				displayName = displayName[:32] + "..."
 			}
 
-			button := tgbotapi.NewInlineKeyboardButtonData(
-				fmt.Sprintf("(Delete) %s - ±%.1f%%", displayName, alert.ThresholdPct),
+			condition := fmt.Sprintf("%s/%s", FormatThreshold(alert.Direction, alert.ThresholdUnit, alert.Threshold), FormatDuration(alert.Window))
+			switch alert.AlertType {
+			case storage.AlertTypeCrossing:
+				condition = fmt.Sprintf("%s %.2f", alert.LevelDirection, alert.LevelPrice)
+			case storage.AlertTypeVolume:
+				condition = FormatVolumeCondition(alert.VolumeMultiple, alert.PrintNotional, alert.Window)
+			case storage.AlertTypeSpread, storage.AlertTypeLiquidity:
+				condition = FormatBookCondition(alert.AlertType, alert.SpreadCents, alert.MinLiquidity)
+			case storage.AlertTypeConsistency:
+				condition = FormatConsistencyCondition(alert.BandCents)
+			}
+
+			deleteButton := tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("(Delete) %s - %s", displayName, condition),
 				fmt.Sprintf("%s_%d", CallbackDeleteAlert, alert.ID),
 			)
-			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+			editButton := tgbotapi.NewInlineKeyboardButtonData("✏️", fmt.Sprintf("%s_%d", CallbackEditAlert, alert.ID))
+			if alert.Archived {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(deleteButton))
+				continue
+			}
+
+			statusButton := tgbotapi.NewInlineKeyboardButtonData("⏸", fmt.Sprintf("%s_%d", CallbackPauseAlert, alert.ID))
+			if alert.Paused {
+				statusButton = tgbotapi.NewInlineKeyboardButtonData("▶️", fmt.Sprintf("%s_%d", CallbackResumeAlert, alert.ID))
+			} else {
+				hasActive = true
+			}
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(deleteButton, editButton, statusButton))
 		}
 	}
 
+	footer := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("📜 History", fmt.Sprintf("%s_0_0", CallbackHistory)),
+	}
+	if hasActive {
+		footer = append(footer, tgbotapi.NewInlineKeyboardButtonData("⏸ Pause All", CallbackPauseAll))
+	}
+	rows = append(rows, footer)
+
 	// Add back button
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
@@ -71,6 +325,153 @@ func BuildAlertListMenu(alerts map[string][]AlertInfo) tgbotapi.InlineKeyboardMa
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
+// BuildEditAlertMenu creates the settings card menu of an alert
+// Price-level and order book alerts have no spike settings, so they only get the delete and back buttons;
+// volume alerts only have a window to change
+func BuildEditAlertMenu(alert *storage.Alert) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	alertID := alert.ID
+
+	switch {
+	case alert.IsArchived():
+	case alert.AlertType == storage.AlertTypeVolume:
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Window", fmt.Sprintf("%s_%d", CallbackEditWindow, alertID)),
+		))
+	case alert.AlertType == storage.AlertTypeSpike:
+		rows = append(rows,
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Threshold", fmt.Sprintf("%s_%d", CallbackEditThreshold, alertID)),
+				tgbotapi.NewInlineKeyboardButtonData("Direction", fmt.Sprintf("%s_%d", CallbackEditDirection, alertID)),
+				tgbotapi.NewInlineKeyboardButtonData("Window", fmt.Sprintf("%s_%d", CallbackEditWindow, alertID)),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔇 Noise Filters", fmt.Sprintf("%s_%d", CallbackEditFilters, alertID)),
+			),
+		)
+	}
+
+	if alert.IsMuted(time.Now()) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔔 Unmute", fmt.Sprintf("%s_%d", CallbackUnmuteAlert, alertID)),
+		))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📜 History", fmt.Sprintf("%s_%d_0", CallbackHistory, alertID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Delete", fmt.Sprintf("%s_%d", CallbackDeleteAlert, alertID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Alerts", CallbackMyAlerts),
+		),
+	)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// BuildNoiseFilterMenu creates the menu to set the minimum trade size and confirmation polls of a spike alert
+// The current settings are checked
+func BuildNoiseFilterMenu(alert *storage.Alert) tgbotapi.InlineKeyboardMarkup {
+	sizeButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(checkedLabel("Any size", alert.MinTradeSize == 0), fmt.Sprintf("%s_%d_0", CallbackFilterSize, alert.ID)),
+	}
+	for _, size := range MinTradeSizes {
+		sizeButtons = append(sizeButtons, tgbotapi.NewInlineKeyboardButtonData(
+			checkedLabel(fmt.Sprintf("≥ %d", size), alert.MinTradeSize == float64(size)),
+			fmt.Sprintf("%s_%d_%d", CallbackFilterSize, alert.ID, size),
+		))
+	}
+
+	var pollButtons []tgbotapi.InlineKeyboardButton
+	for _, polls := range ConfirmPolls {
+		label := fmt.Sprintf("%d polls", polls)
+		if polls == 1 {
+			label = "1 poll"
+		}
+		current := alert.ConfirmPolls == polls || (polls == 1 && alert.ConfirmPolls < 1)
+		pollButtons = append(pollButtons, tgbotapi.NewInlineKeyboardButtonData(
+			checkedLabel(label, current),
+			fmt.Sprintf("%s_%d_%d", CallbackFilterPolls, alert.ID, polls),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		sizeButtons,
+		pollButtons,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Alert", fmt.Sprintf("%s_%d", CallbackEditAlert, alert.ID)),
+		),
+	)
+}
+
+// checkedLabel prefixes the label of the selected option with a check mark
+func checkedLabel(label string, checked bool) string {
+	if checked {
+		return "✅ " + label
+	}
+	return label
+}
+
+// BuildNotificationMenu creates the quick actions attached to an alert notification
+func BuildNotificationMenu(alert *storage.Alert) tgbotapi.InlineKeyboardMarkup {
+	var snooze []tgbotapi.InlineKeyboardButton
+	for _, d := range SnoozeDurations {
+		snooze = append(snooze, tgbotapi.NewInlineKeyboardButtonData(
+			"😴 "+FormatDuration(d),
+			fmt.Sprintf("%s_%d_%d", CallbackSnooze, alert.ID, int(d.Seconds())),
+		))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{snooze}
+
+	// Only spike alerts have a threshold to raise
+	if raised, ok := raisedThreshold(alert); ok {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				"📈 Threshold → "+FormatThreshold(alert.Direction, alert.ThresholdUnit, raised),
+				fmt.Sprintf("%s_%d", CallbackRaiseThreshold, alert.ID),
+			),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔕 Mute until tomorrow", fmt.Sprintf("%s_%d", CallbackMuteTomorrow, alert.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("📜 History", fmt.Sprintf("%s_%d", CallbackAlertHistory, alert.ID)),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// BuildHistoryMenu creates the pagination menu of an alert history page
+// An alertID of 0 pages through the history of all of the user's alerts
+func BuildHistoryMenu(alertID int64, page, totalPages int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("« Newer", fmt.Sprintf("%s_%d_%d", CallbackHistory, alertID, page-1)))
+	}
+	if page+1 < totalPages {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Older »", fmt.Sprintf("%s_%d_%d", CallbackHistory, alertID, page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	if alertID != 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Alert", fmt.Sprintf("%s_%d", CallbackEditAlert, alertID)),
+		))
+	} else {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Alerts", CallbackMyAlerts),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
 // BuildConfirmDeleteMenu creates a confirmation menu for alert deletion
 func BuildConfirmDeleteMenu(alertID int64) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
@@ -122,8 +523,15 @@ func BuildMarketSelectionMenu(featuredMarkets []FeaturedMarket) tgbotapi.InlineK
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
-// BuildThresholdSelectionMenu creates a menu with common threshold values
-func BuildThresholdSelectionMenu() tgbotapi.InlineKeyboardMarkup {
+// BuildThresholdSelectionMenu creates a menu with common threshold values in the given unit
+func BuildThresholdSelectionMenu(unit string) tgbotapi.InlineKeyboardMarkup {
+	switch unit {
+	case storage.ThresholdUnitCents:
+		return buildCentsThresholdMenu()
+	case storage.ThresholdUnitSigma:
+		return buildSigmaThresholdMenu()
+	}
+
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("1%", fmt.Sprintf("%s_1", CallbackSelectThreshold)),
@@ -138,6 +546,298 @@ func BuildThresholdSelectionMenu() tgbotapi.InlineKeyboardMarkup {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📝 Enter Custom Value", CallbackCustomThreshold),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("¢ Use cents instead", fmt.Sprintf("%s_%s", CallbackThresholdUnit, storage.ThresholdUnitCents)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("σ Adapt to volatility", fmt.Sprintf("%s_%s", CallbackThresholdUnit, storage.ThresholdUnitSigma)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// buildCentsThresholdMenu creates a menu with common absolute thresholds in cents
+func buildCentsThresholdMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("1¢", fmt.Sprintf("%s_1", CallbackSelectCents)),
+			tgbotapi.NewInlineKeyboardButtonData("2¢", fmt.Sprintf("%s_2", CallbackSelectCents)),
+			tgbotapi.NewInlineKeyboardButtonData("3¢", fmt.Sprintf("%s_3", CallbackSelectCents)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("5¢", fmt.Sprintf("%s_5", CallbackSelectCents)),
+			tgbotapi.NewInlineKeyboardButtonData("10¢", fmt.Sprintf("%s_10", CallbackSelectCents)),
+			tgbotapi.NewInlineKeyboardButtonData("20¢", fmt.Sprintf("%s_20", CallbackSelectCents)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Enter Custom Value", CallbackCustomThreshold),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("% Use percent instead", fmt.Sprintf("%s_%s", CallbackThresholdUnit, storage.ThresholdUnitPercent)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("σ Adapt to volatility", fmt.Sprintf("%s_%s", CallbackThresholdUnit, storage.ThresholdUnitSigma)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// buildSigmaThresholdMenu creates a menu with common adaptive thresholds in standard deviations
+func buildSigmaThresholdMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("2σ", fmt.Sprintf("%s_2", CallbackSelectSigma)),
+			tgbotapi.NewInlineKeyboardButtonData("2.5σ", fmt.Sprintf("%s_2.5", CallbackSelectSigma)),
+			tgbotapi.NewInlineKeyboardButtonData("3σ", fmt.Sprintf("%s_3", CallbackSelectSigma)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("4σ", fmt.Sprintf("%s_4", CallbackSelectSigma)),
+			tgbotapi.NewInlineKeyboardButtonData("5σ", fmt.Sprintf("%s_5", CallbackSelectSigma)),
+			tgbotapi.NewInlineKeyboardButtonData("6σ", fmt.Sprintf("%s_6", CallbackSelectSigma)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Enter Custom Value", CallbackCustomThreshold),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("% Use percent instead", fmt.Sprintf("%s_%s", CallbackThresholdUnit, storage.ThresholdUnitPercent)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildOutcomeSelectionMenu creates a menu listing every outcome of a multi-outcome market
+func BuildOutcomeSelectionMenu(outcomes []OutcomeOption) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	for i, outcome := range outcomes {
+		// Truncate long outcome names so the price stays visible
+		displayName := outcome.Name
+		if len(displayName) > 35 {
+			displayName = displayName[:32] + "..."
+		}
+
+		label := fmt.Sprintf("%s — n/a", displayName)
+		if outcome.Price > 0 {
+			label = fmt.Sprintf("%s — $%.4f", displayName, outcome.Price)
+		}
+
+		// Outcomes are referenced by index because token IDs exceed Telegram's 64-byte callback limit
+		button := tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%s_%d", CallbackSelectOutcome, i))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📋 All Outcomes", fmt.Sprintf("%s_%s", CallbackSelectOutcome, OutcomeAll)),
+	))
+
+	// Add back button
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// AlertCooldowns are the per-alert cooldowns offered in addition to the global default
+var AlertCooldowns = []time.Duration{
+	time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+// BuildDirectionSelectionMenu creates a button row to choose the direction of a spike alert
+func BuildDirectionSelectionMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬆️ Up only", fmt.Sprintf("%s_%s", CallbackSelectDirection, storage.DirectionUp)),
+			tgbotapi.NewInlineKeyboardButtonData("⬇️ Down only", fmt.Sprintf("%s_%s", CallbackSelectDirection, storage.DirectionDown)),
+			tgbotapi.NewInlineKeyboardButtonData("↕️ Both", fmt.Sprintf("%s_%s", CallbackSelectDirection, storage.DirectionBoth)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildWindowSelectionMenu creates a menu with the available lookback windows
+func BuildWindowSelectionMenu() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, window := range AlertWindows {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			FormatDuration(window),
+			fmt.Sprintf("%s_%d", CallbackSelectWindow, int(window.Seconds())),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildCooldownSelectionMenu creates a menu with the available notification cooldowns
+func BuildCooldownSelectionMenu(defaultCooldown time.Duration) tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, cooldown := range AlertCooldowns {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			FormatDuration(cooldown),
+			fmt.Sprintf("%s_%d", CallbackSelectCooldown, int(cooldown.Seconds())),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("Default (%s)", FormatDuration(defaultCooldown)),
+				fmt.Sprintf("%s_%s", CallbackSelectCooldown, CooldownDefault),
+			),
+		),
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildAlertTypeSelectionMenu creates a menu with the available alert types
+func BuildAlertTypeSelectionMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📈 Price Spike", fmt.Sprintf("%s_%s", CallbackSelectType, storage.AlertTypeSpike)),
+			tgbotapi.NewInlineKeyboardButtonData("🎯 Price Level", fmt.Sprintf("%s_%s", CallbackSelectType, storage.AlertTypeCrossing)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📊 Volume", fmt.Sprintf("%s_%s", CallbackSelectType, storage.AlertTypeVolume)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↔️ Spread", fmt.Sprintf("%s_%s", CallbackSelectType, storage.AlertTypeSpread)),
+			tgbotapi.NewInlineKeyboardButtonData("💧 Liquidity", fmt.Sprintf("%s_%s", CallbackSelectType, storage.AlertTypeLiquidity)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⚖️ Consistency", fmt.Sprintf("%s_%s", CallbackSelectType, storage.AlertTypeConsistency)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildVolumeMultipleMenu creates a menu with common volume surge multiples
+// "Off" creates an alert on large trades only
+func BuildVolumeMultipleMenu() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, multiple := range VolumeMultiples {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%d×", multiple),
+			fmt.Sprintf("%s_%d", CallbackSelectMultiple, multiple),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Off (large trades only)", fmt.Sprintf("%s_%s", CallbackSelectMultiple, SettingOff)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildTradeValueMenu creates a menu with common large trade values
+// "Off" is only offered if the alert already watches for volume surges
+func BuildTradeValueMenu(allowOff bool) tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, value := range TradeValues {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			FormatVolume(float64(value)),
+			fmt.Sprintf("%s_%d", CallbackSelectTrade, value),
+		))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{buttons}
+	if allowOff {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Off", fmt.Sprintf("%s_%s", CallbackSelectTrade, SettingOff)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// BuildSpreadMenu creates a menu with common spread limits
+func BuildSpreadMenu() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, cents := range SpreadLimits {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%d¢", cents),
+			fmt.Sprintf("%s_%d", CallbackSelectSpread, cents),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildLiquidityMenu creates a menu with common top-of-book liquidity limits
+func BuildLiquidityMenu() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, value := range LiquidityLimits {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			FormatVolume(float64(value)),
+			fmt.Sprintf("%s_%d", CallbackSelectLiquidity, value),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildBandMenu creates a menu with common consistency bands around $1
+func BuildBandMenu() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, cents := range ConsistencyBands {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("±%d¢", cents),
+			fmt.Sprintf("%s_%d", CallbackSelectBand, cents),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
+		),
+	)
+}
+
+// BuildLevelDirectionMenu creates a menu to choose the crossing direction of a level alert
+func BuildLevelDirectionMenu() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬆️ Crosses Above", fmt.Sprintf("%s_%s", CallbackSelectLevel, storage.CrossAbove)),
+			tgbotapi.NewInlineKeyboardButtonData("⬇️ Drops Below", fmt.Sprintf("%s_%s", CallbackSelectLevel, storage.CrossBelow)),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("« Back to Menu", "back_to_menu"),
 		),
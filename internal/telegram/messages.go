@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/qmitry/opinion-alert-bot/internal/api"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
 )
 
 const (
@@ -25,35 +28,105 @@ The bot tracks market prices and alerts you when prices make changes and spikes.
 <b>Commands:</b>
 /start - Show main menu
 /help - Show this help message
+/history - Show recently triggered alerts
+/settings - Set your timezone, quiet hours and urgent threshold
 
 <b>Creating Alerts:</b>
 1. Click "Create Alert"
 2. Enter the market ID (you can find it in the URL as topicId when the market is open on Opinion.Trade)
-3. Enter your minimum price change threshold (e.g., 20 for ±20%)
+3. For multi-outcome markets, pick the outcome to watch (or all of them)
+4. Choose the alert type:
+   • <b>Price Spike</b> - enter the minimum price change threshold in percent (e.g., 20 for ±20%), in cents (e.g., 5 for ±$0.05) or in standard deviations (e.g., 3 for ±3σ, adapting to how volatile the market usually is; notifications show the move's z-score), choose up-only, down-only or both directions, pick the time window (1m to 24h) and a cooldown between notifications (a move is reported once until the price settles back)
+   • <b>Price Level</b> - enter a price level (e.g., 0.70) and whether to alert when the price crosses above or drops below it
+   • <b>Spread</b> / <b>Liquidity</b> - enter the spread in cents or the dollar value of the best bid and ask to alert at. Thin books are where spikes are least reliable
+   • <b>Consistency</b> - choose how far from $1 the outcome prices may add up to: YES + NO for binary markets, the YES prices of all outcomes for multi-outcome markets. The alert covers the whole market and lists the legs that are off
+   • <b>Volume</b> - choose how many times its 24h average the volume traded within the window must reach, and/or the value of a single trade to alert on, then pick the window and cooldown
+
+<b>Managing Alerts:</b>
+Open "My Alerts" and use the buttons next to an alert:
+- ✏️ changes the threshold, direction or window of a spike alert without losing its history, and sets its noise filters: a minimum trade size and a number of polls a move has to hold for. Triggers held back by a filter show up in the history marked 🔇
+- ⏸ pauses the alert and ▶️ resumes it. Paused alerts keep their history and don't count toward the market limit
+- 📜 History lists when the alert was triggered
+
+Every notification also has quick actions to snooze the alert for 15m, 1h or 24h, raise its threshold, mute it until tomorrow or open its history.
+- "Pause All" pauses every alert at once
+
+<b>Quiet Hours:</b>
+Set your timezone and quiet hours in Settings. Alerts raised during quiet hours are held and sent as one summary when they end. Alerts above your urgent threshold are still sent immediately.
+
+<b>Digest:</b>
+Switch to an hourly or daily digest in Settings to get one summary per period with each market's open, close, high and low and the biggest moves instead of real-time alerts.
+
+<b>Cutoff Reminders:</b>
+Turn on reminders in Settings to be told 24h, 6h, 1h or 10m before a tracked market stops trading, with its current price and 24h volume.
 
 <b>Limits:</b>
-- Maximum 10 markets per user
+- Maximum 10 markets per user (paused alerts don't count)
 - Unlimited alerts per market`
 
-	MsgSelectMarket      = "Select a market to create an alert, or enter a custom market ID:"
-	MsgMarketIDPrompt    = "Please enter the Opinion.Trade market ID:\n\nTip: You can find the market ID in the URL as topicId when viewing a market on Opinion.Trade (e.g., app.opinion.trade/detail?<b>topicId=1098</b> → market ID is 1098)"
-	MsgThresholdPrompt   = "Enter the minimum price change threshold percentage for 1 minute (e.g., 20 for ±20%):"
-	MsgAlertCreated      = "Alert created successfully! You'll be notified when the price changes by ±%.1f%% within 1 minute."
-	MsgAlertDeleted      = "Alert deleted successfully."
-	MsgInvalidMarketID   = "Invalid market ID. Please enter a valid market ID."
-	MsgInvalidThreshold  = "Invalid threshold. Please enter a number between 1 and 100."
-	MsgMaxMarketsReached = "You've reached the maximum of 10 tracked markets. Delete an alert for a market you no longer want to track."
-	MsgNoAlerts          = "You don't have any alerts set up yet. Click 'Create Alert' to get started!"
-	MsgNoMarketsTracked  = "You're not tracking any markets yet."
-	MsgConfirmDelete     = "Are you sure you want to delete this alert?"
-	MsgCancelled         = "Operation cancelled."
-	MsgUnknownCommand    = "Unknown command. Use /start to see available options."
-	MsgErrorOccurred     = "An error occurred. Please try again later."
-	MsgMarketNotFound    = "Market not found. Please check the market ID and try again."
+	MsgSelectMarket       = "Select a market to create an alert, or enter a custom market ID:"
+	MsgMarketIDPrompt     = "Please enter the Opinion.Trade market ID:\n\nTip: You can find the market ID in the URL as topicId when viewing a market on Opinion.Trade (e.g., app.opinion.trade/detail?<b>topicId=1098</b> → market ID is 1098)"
+	MsgThresholdPrompt    = "Enter the minimum price change threshold percentage (e.g., 20 for ±20%):"
+	MsgThresholdCents     = "Enter the minimum price change in cents (e.g., 5 for a move of $0.05):"
+	MsgThresholdSigma     = "Enter the minimum price change in standard deviations of the market's recent moves (e.g., 3 for ±3σ). The alert adapts to how volatile the market usually is:"
+	MsgDirectionPrompt    = "Alert on moves in which direction?"
+	MsgWindowPrompt       = "Select the time window the price change is measured over:"
+	MsgCooldownPrompt     = "Select the minimum time between two notifications for this alert:"
+	MsgOutcomePrompt      = "This market has multiple outcomes. Select the outcome to watch, or watch all of them:"
+	MsgAlertTypePrompt    = "What should this alert watch for?\n\n📈 <b>Price Spike</b> - the price moves by a percentage within a time window\n🎯 <b>Price Level</b> - the price crosses above or below a level\n📊 <b>Volume</b> - trading volume surges or a single large trade happens\n↔️ <b>Spread</b> - the gap between the best bid and ask widens\n💧 <b>Liquidity</b> - the orders at the best bid or ask thin out\n⚖️ <b>Consistency</b> - the outcome prices stop adding up to $1"
+	MsgLevelPrompt        = "Enter the price level between 0.01 and 0.99 (e.g., 0.70):"
+	MsgLevelDirection     = "Alert when the price crosses this level in which direction?"
+	MsgInvalidLevel       = "Invalid level. Please enter a price between 0.01 and 0.99."
+	MsgMultiplePrompt     = "Alert when the volume traded within the window reaches how many times its usual amount (the 24h average)? Choose an option or type a multiple (e.g., 4):"
+	MsgInvalidMultiple    = "Invalid multiple. Please enter a number between 1.5 and 100."
+	MsgTradeValuePrompt   = "Also alert on any single trade worth at least:"
+	MsgSpreadPrompt       = "Alert when the bid-ask spread widens to at least how many cents? Choose an option or type a number (e.g., 4):"
+	MsgInvalidSpread      = "Invalid spread. Please enter a number of cents between 0.1 and 99."
+	MsgLiquidityPrompt    = "Alert when the orders at the best bid or ask are worth less than how many dollars? Choose an option or type an amount (e.g., 250):"
+	MsgInvalidLiquidity   = "Invalid amount. Please enter a dollar amount between 1 and 1000000."
+	MsgBandPrompt         = "The outcome prices of a market should add up to about $1 (YES + NO, or the YES prices of all outcomes). Alert when they add up to more than how many cents away from $1? Choose an option or type a number (e.g., 4):"
+	MsgInvalidBand        = "Invalid band. Please enter a number of cents between 0.5 and 50."
+	MsgFiltersPrompt      = "Ignore moves set by trades smaller than a number of shares, and wait for a move to hold for several polls in a row before alerting. Triggers held back by a filter are listed in the alert's history:"
+	MsgVolumeWindowPrompt = "Select the time window the volume is measured over (trades older than the window are ignored):"
+	MsgAlertCreated       = "Alert created successfully! You'll be notified when the price changes by ±%.1f%% within %s."
+	MsgAlertDeleted       = "Alert deleted successfully."
+	MsgInvalidMarketID    = "Invalid market ID. Please enter a valid market ID."
+	MsgInvalidThreshold   = "Invalid threshold. Please enter a number between 1 and 100."
+	MsgInvalidCents       = "Invalid threshold. Please enter a number of cents between 0.1 and 99."
+	MsgInvalidSigma       = "Invalid threshold. Please enter a number of standard deviations between 1 and 10."
+	MsgAlertNotFound      = "Alert not found. It may have been deleted."
+	MsgAlertUpdated       = "✅ Alert updated."
+	MsgAlertPaused        = "⏸ Alert paused. Its history is kept and you can resume it at any time."
+	MsgAlertResumed       = "▶️ Alert resumed."
+	MsgAlertArchived      = "🗄 This alert was archived because its market resolved. Create a new alert to track another market."
+	MsgMarketResolved     = "🗄 This market has resolved and can no longer be tracked."
+	MsgAlertsPaused       = "⏸ Paused %d alerts."
+	MsgNoHistory          = "No alerts have been triggered yet."
+	MsgAlertSnoozed       = "😴 Alert snoozed until %s."
+	MsgAlertUnmuted       = "🔔 Alert unmuted."
+	MsgThresholdRaised    = "📈 Threshold raised to %s."
+	MsgThresholdAtMax     = "The threshold is already at its maximum."
+	MsgTimezonePrompt     = "Enter your timezone as a region name (e.g., Europe/Berlin, America/New_York or UTC):"
+	MsgInvalidTimezone    = "Unknown timezone. Please enter a region name such as Europe/Berlin or UTC."
+	MsgQuietHoursPrompt   = "Choose when alerts should be held back. Alerts raised during quiet hours are sent as one summary when they end:"
+	MsgQuietHoursCustom   = "Enter your quiet hours as start and end hour (e.g., 22-7 for 22:00 to 07:00):"
+	MsgInvalidQuietHours  = "Invalid quiet hours. Please enter two different hours between 0 and 23 (e.g., 22-7)."
+	MsgUrgentPrompt       = "Alerts with a price change at least this large are sent even during quiet hours:"
+	MsgSettingsUpdated    = "✅ Settings updated."
+	MsgRemindersPrompt    = "Get a reminder before a tracked market's trading cutoff. Tap an option to turn it on or off:"
+	MsgDigestPrompt       = "Get a summary instead of real-time alerts? Triggered alerts are collected and sent as one digest per period. Alerts above your urgent threshold are still sent immediately:"
+	MsgMaxMarketsReached  = "You've reached the maximum of 10 tracked markets. Delete an alert for a market you no longer want to track."
+	MsgNoAlerts           = "You don't have any alerts set up yet. Click 'Create Alert' to get started!"
+	MsgNoMarketsTracked   = "You're not tracking any markets yet."
+	MsgConfirmDelete      = "Are you sure you want to delete this alert?"
+	MsgCancelled          = "Operation cancelled."
+	MsgUnknownCommand     = "Unknown command. Use /start to see available options."
+	MsgErrorOccurred      = "An error occurred. Please try again later."
+	MsgMarketNotFound     = "Market not found. Please check the market ID and try again."
 )
 
-// FormatAlertNotification formats a price spike alert message
-func FormatAlertNotification(marketTitle, marketID string, previousPrice, currentPrice, changePct, threshold float64) string {
+// FormatAlertNotification formats a price spike alert message. zScore is the move in standard deviations of adaptive alerts
+func FormatAlertNotification(alert *storage.Alert, marketTitle string, previousPrice, currentPrice, changePct, zScore float64) string {
 	// Choose color indicator based on direction
 	var colorIndicator string
 	if changePct > 0 {
@@ -67,7 +140,23 @@ func FormatAlertNotification(marketTitle, marketID string, previousPrice, curren
 		direction = ""
 	}
 
-	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", marketID)
+	// Include the watched outcome for multi-outcome markets
+	if alert.OutcomeName != "" {
+		marketTitle = fmt.Sprintf("%s — %s", marketTitle, alert.OutcomeName)
+	}
+
+	// Cents alerts also show the absolute move in price points
+	// and adaptive alerts how many standard deviations it was
+	centsChange := ""
+	switch alert.ThresholdUnit {
+	case storage.ThresholdUnitCents:
+		centsChange = fmt.Sprintf(" (%s%.1f¢)", direction, (currentPrice-previousPrice)*100)
+	case storage.ThresholdUnitSigma:
+		centsChange = fmt.Sprintf(" (z = %s%.1fσ)", direction, zScore)
+	}
+
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", alert.MarketID)
+	window := FormatDuration(alert.Window())
 
 	return fmt.Sprintf(`📈 <b>Price Spike Alert!</b>
 
@@ -75,24 +164,456 @@ func FormatAlertNotification(marketTitle, marketID string, previousPrice, curren
 
 💵 <b>Price Movement:</b>
    • Now: $%.4f
-   • 1m ago: $%.4f
-   • Change: %s %s%.2f%%
+   • %s ago: $%.4f
+   • Change: %s %s%.2f%%%s
 
 ⚙️ <b>Alert Settings:</b>
-   • Threshold: ±%.1f%%
+   • Threshold: %s in %s
+   • Direction: %s
    • Triggered: %s UTC`,
 		marketURL,
 		marketTitle,
 		currentPrice,
+		window,
 		previousPrice,
 		colorIndicator,
 		direction,
 		changePct,
-		threshold,
+		centsChange,
+		FormatThreshold(alert.Direction, alert.ThresholdUnit, alert.ThresholdValue()),
+		window,
+		DirectionLabel(alert.Direction),
+		time.Now().UTC().Format("15:04:05"),
+	)
+}
+
+// FormatMarketStatusNotification formats the notice that a tracked market closed, resolved or otherwise
+// changed status. archived is the number of the user's alerts archived because the market resolved
+func FormatMarketStatusNotification(previous, market *storage.Market, archived int) string {
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", market.MarketID)
+	title := market.Title
+	if title == "" {
+		title = "Market #" + market.MarketID
+	}
+
+	var sb strings.Builder
+	switch {
+	case market.IsResolved():
+		sb.WriteString("🏁 <b>Market Resolved</b>\n\n")
+		sb.WriteString(fmt.Sprintf("📌 <b>Market:</b> <a href=\"%s\">%s</a>\n\n", marketURL, title))
+		if winner := market.WinningOutcome(); winner != "" {
+			sb.WriteString(fmt.Sprintf("🏆 Winning outcome: <b>%s</b>\n", winner))
+		} else {
+			sb.WriteString("The winning outcome hasn't been published yet.\n")
+		}
+	case market.StatusLabel(time.Now()) == storage.MarketStatusClosed:
+		sb.WriteString("⏹ <b>Trading Closed</b>\n\n")
+		sb.WriteString(fmt.Sprintf("📌 <b>Market:</b> <a href=\"%s\">%s</a>\n\n", marketURL, title))
+		sb.WriteString("The market has passed its cutoff and is awaiting resolution.\n")
+	default:
+		sb.WriteString("ℹ️ <b>Market Status Changed</b>\n\n")
+		sb.WriteString(fmt.Sprintf("📌 <b>Market:</b> <a href=\"%s\">%s</a>\n\n", marketURL, title))
+		sb.WriteString(fmt.Sprintf("Status: %s → <b>%s</b>\n", previous.StatusName(), market.StatusName()))
+	}
+
+	if archived > 0 {
+		sb.WriteString(fmt.Sprintf("\n🗄 Your %d alert(s) on this market were archived and no longer count toward your market limit.", archived))
+	}
+
+	return sb.String()
+}
+
+// FormatCrossingNotification formats a price level crossing alert message
+func FormatCrossingNotification(alert *storage.Alert, marketTitle string, previousPrice, currentPrice float64) string {
+	colorIndicator := "🟢"
+	action := "crossed above"
+	if alert.LevelDirection == storage.CrossBelow {
+		colorIndicator = "🔴"
+		action = "dropped below"
+	}
+
+	// Include the watched outcome for multi-outcome markets
+	if alert.OutcomeName != "" {
+		marketTitle = fmt.Sprintf("%s — %s", marketTitle, alert.OutcomeName)
+	}
+
+	var level float64
+	if alert.LevelPrice != nil {
+		level = *alert.LevelPrice
+	}
+
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", alert.MarketID)
+
+	return fmt.Sprintf(`🎯 <b>Price Level Alert!</b>
+
+📌 <b>Market:</b> <a href="%s">%s</a>
+
+%s Price %s <b>$%.4f</b>
+   • Now: $%.4f
+   • Previous: $%.4f
+
+⚙️ <b>Alert Settings:</b>
+   • Level: %s $%.4f
+   • Triggered: %s UTC`,
+		marketURL,
+		marketTitle,
+		colorIndicator,
+		action,
+		level,
+		currentPrice,
+		previousPrice,
+		alert.LevelDirection,
+		level,
+		time.Now().UTC().Format("15:04:05"),
+	)
+}
+
+// VolumeTrigger describes what set off a volume alert. Either part is zero if it didn't trigger
+type VolumeTrigger struct {
+	Window        time.Duration
+	WindowVolume  float64 // Volume traded within the window
+	AverageVolume float64 // Average volume per window over the trailing 24 hours
+	TradeSide     string
+	TradeSize     float64 // Shares in the large trade
+	TradePrice    float64
+}
+
+// FormatVolumeNotification formats a volume surge or large trade alert message
+func FormatVolumeNotification(alert *storage.Alert, marketTitle string, trigger VolumeTrigger) string {
+	// Include the watched outcome for multi-outcome markets
+	if alert.OutcomeName != "" {
+		marketTitle = fmt.Sprintf("%s — %s", marketTitle, alert.OutcomeName)
+	}
+
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", alert.MarketID)
+	window := FormatDuration(trigger.Window)
+
+	var sb strings.Builder
+	sb.WriteString("📊 <b>Volume Alert!</b>\n\n")
+	sb.WriteString(fmt.Sprintf("📌 <b>Market:</b> <a href=\"%s\">%s</a>\n\n", marketURL, marketTitle))
+
+	if trigger.WindowVolume > 0 {
+		sb.WriteString("🔥 <b>Volume Surge:</b>\n")
+		sb.WriteString(fmt.Sprintf("   • Last %s: %s\n", window, FormatVolume(trigger.WindowVolume)))
+		sb.WriteString(fmt.Sprintf("   • Usual for %s: %s\n", window, FormatVolume(trigger.AverageVolume)))
+		sb.WriteString(fmt.Sprintf("   • %.1f× the 24h average\n\n", trigger.WindowVolume/trigger.AverageVolume))
+	}
+
+	if trigger.TradeSize > 0 {
+		side := strings.ToUpper(trigger.TradeSide)
+		if side == "" {
+			side = "TRADE"
+		}
+		sb.WriteString("🐋 <b>Large Trade:</b>\n")
+		sb.WriteString(fmt.Sprintf("   • %s %.0f shares @ $%.4f\n", side, trigger.TradeSize, trigger.TradePrice))
+		sb.WriteString(fmt.Sprintf("   • Value: %s\n\n", FormatVolume(trigger.TradeSize*trigger.TradePrice)))
+	}
+
+	sb.WriteString("⚙️ <b>Alert Settings:</b>\n")
+	sb.WriteString(fmt.Sprintf("   • Condition: %s\n", FormatVolumeCondition(alert.VolumeMultiple, alert.PrintNotional, alert.Window())))
+	sb.WriteString(fmt.Sprintf("   • Triggered: %s UTC", time.Now().UTC().Format("15:04:05")))
+
+	return sb.String()
+}
+
+// FormatVolumeCondition describes when a volume alert fires (e.g. "3× avg in 5m or trade ≥ $5.0K")
+func FormatVolumeCondition(multiple, notional float64, window time.Duration) string {
+	var parts []string
+	if multiple > 0 {
+		parts = append(parts, fmt.Sprintf("%g× avg in %s", multiple, FormatDuration(window)))
+	}
+	if notional > 0 {
+		parts = append(parts, "trade ≥ "+FormatVolume(notional))
+	}
+	return strings.Join(parts, " or ")
+}
+
+// FormatBookNotification formats a spread or liquidity alert message
+func FormatBookNotification(alert *storage.Alert, marketTitle string, book *storage.BookSnapshot) string {
+	// Include the watched outcome for multi-outcome markets
+	if alert.OutcomeName != "" {
+		marketTitle = fmt.Sprintf("%s — %s", marketTitle, alert.OutcomeName)
+	}
+
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", alert.MarketID)
+
+	heading := "↔️ <b>Spread Alert!</b>"
+	if alert.AlertType == storage.AlertTypeLiquidity {
+		heading = "💧 <b>Liquidity Alert!</b>"
+	}
+
+	spread := "n/a (one side empty)"
+	if book.HasBothSides() {
+		spread = fmt.Sprintf("%.1f¢", book.SpreadCents())
+	}
+
+	return fmt.Sprintf(`%s
+
+📌 <b>Market:</b> <a href="%s">%s</a>
+
+📖 <b>Order Book:</b>
+   • Bid: %s
+   • Ask: %s
+   • Spread: %s
+   • Depth within %.0f¢: %s bid / %s ask
+
+⚙️ <b>Alert Settings:</b>
+   • Condition: %s
+   • Triggered: %s UTC`,
+		heading,
+		marketURL,
+		marketTitle,
+		formatBookLevel(book.BestBid, book.BidSize),
+		formatBookLevel(book.BestAsk, book.AskSize),
+		spread,
+		api.BookDepthBand*100,
+		FormatVolume(book.BidDepth),
+		FormatVolume(book.AskDepth),
+		FormatBookCondition(alert.AlertType, alert.SpreadCents, alert.MinLiquidity),
 		time.Now().UTC().Format("15:04:05"),
 	)
 }
 
+// formatBookLevel formats the best price of one side of an order book (e.g. "$0.4500 × 1200 ($540)")
+func formatBookLevel(price, size float64) string {
+	if price == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("$%.4f × %.0f (%s)", price, size, FormatVolume(price*size))
+}
+
+// FormatBookCondition describes when a spread or liquidity alert fires (e.g. "spread ≥ 5¢")
+func FormatBookCondition(alertType string, spreadCents, minLiquidity float64) string {
+	if alertType == storage.AlertTypeLiquidity {
+		return "top of book < " + FormatVolume(minLiquidity)
+	}
+	return fmt.Sprintf("spread ≥ %g¢", spreadCents)
+}
+
+// ConsistencyLeg is one outcome price of a market checked for consistency
+type ConsistencyLeg struct {
+	Label string
+	Price float64 // Last traded price
+	Fair  float64 // Price rescaled so that all legs add up to $1
+	Off   bool    // The leg is far enough from its fair price to be out of line
+}
+
+// FormatConsistencyNotification formats an alert that a market's outcome prices no longer add up to $1
+func FormatConsistencyNotification(alert *storage.Alert, marketTitle string, legs []ConsistencyLeg, sum float64) string {
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", alert.MarketID)
+
+	var lines strings.Builder
+	for _, leg := range legs {
+		marker := ""
+		if leg.Off {
+			marker = "⚠️ "
+		}
+		lines.WriteString(fmt.Sprintf("\n   • %s%s: $%.4f (fair $%.4f, %+.1f¢)", marker, leg.Label, leg.Price, leg.Fair, (leg.Price-leg.Fair)*100))
+	}
+
+	return fmt.Sprintf(`⚖️ <b>Consistency Alert!</b>
+
+📌 <b>Market:</b> <a href="%s">%s</a>
+
+🧮 <b>Prices add up to $%.4f</b> (%+.1f¢ from $1)
+%s
+
+⚙️ <b>Alert Settings:</b>
+   • Condition: %s
+   • Triggered: %s UTC`,
+		marketURL,
+		marketTitle,
+		sum,
+		(sum-1)*100,
+		lines.String(),
+		FormatConsistencyCondition(alert.BandCents),
+		time.Now().UTC().Format("15:04:05"),
+	)
+}
+
+// FormatConsistencyCondition describes when a consistency alert fires (e.g. "prices sum off $1 by ≥ 5¢")
+func FormatConsistencyCondition(bandCents float64) string {
+	return fmt.Sprintf("prices sum off $1 by ≥ %g¢", bandCents)
+}
+
+// FormatAlertSettings formats the settings card of an alert shown when editing it
+func FormatAlertSettings(alert *storage.Alert, defaultCooldown time.Duration) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("⚙️ <b>Alert #%d</b>\n\n", alert.ID))
+	if alert.IsArchived() {
+		b.WriteString("<b>Status:</b> 🗄 Archived (market resolved)\n")
+	} else if !alert.IsActive {
+		b.WriteString("<b>Status:</b> ⏸ Paused\n")
+	} else if alert.IsMuted(time.Now()) {
+		b.WriteString(fmt.Sprintf("<b>Status:</b> 🔕 Muted until %s\n", alert.MutedUntil.Format("Jan 02 15:04")))
+	}
+	b.WriteString(fmt.Sprintf("<b>Market:</b> %s\n", alert.MarketName))
+	if alert.OutcomeName != "" {
+		b.WriteString(fmt.Sprintf("<b>Outcome:</b> %s\n", alert.OutcomeName))
+	}
+
+	if alert.AlertType == storage.AlertTypeCrossing && alert.LevelPrice != nil {
+		b.WriteString(fmt.Sprintf("<b>Condition:</b> crosses %s $%.2f\n", alert.LevelDirection, *alert.LevelPrice))
+		b.WriteString(fmt.Sprintf("<b>Cooldown:</b> %s\n\n", FormatDuration(alert.Cooldown(defaultCooldown))))
+		b.WriteString("Price-level alerts have no spike settings. Delete the alert and create a new one to change the level.")
+		return b.String()
+	}
+
+	if alert.AlertType == storage.AlertTypeSpread || alert.AlertType == storage.AlertTypeLiquidity {
+		b.WriteString(fmt.Sprintf("<b>Condition:</b> %s\n", FormatBookCondition(alert.AlertType, alert.SpreadCents, alert.MinLiquidity)))
+		b.WriteString(fmt.Sprintf("<b>Cooldown:</b> %s\n\n", FormatDuration(alert.Cooldown(defaultCooldown))))
+		b.WriteString("Order book alerts have no spike settings. Delete the alert and create a new one to change the condition.")
+		return b.String()
+	}
+
+	if alert.AlertType == storage.AlertTypeConsistency {
+		b.WriteString(fmt.Sprintf("<b>Condition:</b> %s\n", FormatConsistencyCondition(alert.BandCents)))
+		b.WriteString(fmt.Sprintf("<b>Cooldown:</b> %s\n\n", FormatDuration(alert.Cooldown(defaultCooldown))))
+		b.WriteString("Consistency alerts have no spike settings. Delete the alert and create a new one to change the band.")
+		return b.String()
+	}
+
+	if alert.AlertType == storage.AlertTypeVolume {
+		b.WriteString(fmt.Sprintf("<b>Condition:</b> %s\n", FormatVolumeCondition(alert.VolumeMultiple, alert.PrintNotional, alert.Window())))
+		b.WriteString(fmt.Sprintf("<b>Window:</b> %s\n", FormatDuration(alert.Window())))
+		b.WriteString(fmt.Sprintf("<b>Cooldown:</b> %s\n\n", FormatDuration(alert.Cooldown(defaultCooldown))))
+		b.WriteString("Choose a setting to change:")
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("<b>Threshold:</b> %s\n", FormatThreshold(alert.Direction, alert.ThresholdUnit, alert.ThresholdValue())))
+	b.WriteString(fmt.Sprintf("<b>Direction:</b> %s\n", DirectionLabel(alert.Direction)))
+	b.WriteString(fmt.Sprintf("<b>Window:</b> %s\n", FormatDuration(alert.Window())))
+	b.WriteString(fmt.Sprintf("<b>Cooldown:</b> %s\n", FormatDuration(alert.Cooldown(defaultCooldown))))
+	b.WriteString(fmt.Sprintf("<b>Noise filters:</b> %s\n\n", FormatNoiseFilters(alert)))
+	b.WriteString("Choose a setting to change:")
+
+	return b.String()
+}
+
+// FormatNoiseFilters describes the noise filters of a spike alert (e.g. "trades ≥ 100 shares, 3 polls")
+func FormatNoiseFilters(alert *storage.Alert) string {
+	if !alert.HasNoiseFilters() {
+		return "off"
+	}
+
+	var parts []string
+	if alert.MinTradeSize > 0 {
+		parts = append(parts, fmt.Sprintf("trades ≥ %g shares", alert.MinTradeSize))
+	}
+	if alert.ConfirmPolls > 1 {
+		parts = append(parts, fmt.Sprintf("%d polls", alert.ConfirmPolls))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatUserSettings formats the settings card of a user
+func FormatUserSettings(user *storage.User) string {
+	loc := user.Location()
+
+	timezone := user.Timezone
+	if timezone == "" {
+		timezone = fmt.Sprintf("%s (server default)", loc.String())
+	}
+
+	quietHours := "Off"
+	if user.HasQuietHours() {
+		quietHours = FormatQuietHours(*user.QuietStart, *user.QuietEnd)
+	}
+
+	urgent := "Off"
+	if user.UrgentThresholdPct != nil {
+		urgent = fmt.Sprintf("±%.1f%%", *user.UrgentThresholdPct)
+	}
+
+	return fmt.Sprintf("⚙️ <b>Settings</b>\n\n<b>Timezone:</b> %s (now %s)\n<b>Quiet hours:</b> %s\n<b>Urgent threshold:</b> %s\n<b>Digest:</b> %s\n<b>Cutoff reminders:</b> %s",
+		timezone, time.Now().In(loc).Format("15:04"), quietHours, urgent, DigestLabel(user.DigestMode), FormatReminderOffsets(user.ReminderOffsets()))
+}
+
+// FormatReminderOffsets formats cutoff reminder offsets (e.g. "24h, 1h before cutoff")
+func FormatReminderOffsets(offsets []time.Duration) string {
+	if len(offsets) == 0 {
+		return "Off"
+	}
+
+	labels := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		labels = append(labels, FormatDuration(offset))
+	}
+	return strings.Join(labels, ", ") + " before cutoff"
+}
+
+// DigestLabel returns a human-readable label for a digest mode
+func DigestLabel(mode string) string {
+	switch mode {
+	case storage.DigestHourly:
+		return "Hourly"
+	case storage.DigestDaily:
+		return fmt.Sprintf("Daily at %02d:00", storage.DailyDigestHour)
+	default:
+		return "Off (real-time alerts)"
+	}
+}
+
+// FormatQuietHours formats a quiet hours window (e.g. 22:00–07:00)
+func FormatQuietHours(start, end int) string {
+	return fmt.Sprintf("%02d:00–%02d:00", start, end)
+}
+
+// FormatThreshold formats a spike threshold with its direction sign and unit (e.g. ±5.0% or +3.0¢)
+func FormatThreshold(direction, unit string, value float64) string {
+	return ThresholdSign(direction) + FormatThresholdValue(unit, value)
+}
+
+// FormatThresholdValue formats a spike threshold value with its unit (e.g. 5.0%, 3.0¢ or 3.0σ)
+func FormatThresholdValue(unit string, value float64) string {
+	switch unit {
+	case storage.ThresholdUnitCents:
+		return fmt.Sprintf("%.1f¢", value)
+	case storage.ThresholdUnitSigma:
+		return fmt.Sprintf("%.1fσ", value)
+	default:
+		return fmt.Sprintf("%.1f%%", value)
+	}
+}
+
+// ThresholdSign returns the sign shown in front of a spike threshold for the given direction
+func ThresholdSign(direction string) string {
+	switch direction {
+	case storage.DirectionUp:
+		return "+"
+	case storage.DirectionDown:
+		return "-"
+	default:
+		return "±"
+	}
+}
+
+// DirectionLabel returns a human-readable label for a spike direction
+func DirectionLabel(direction string) string {
+	switch direction {
+	case storage.DirectionUp:
+		return "Up only"
+	case storage.DirectionDown:
+		return "Down only"
+	default:
+		return "Both"
+	}
+}
+
+// FormatDuration formats a window or cooldown as a short label (e.g. 1m, 1h, 24h)
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
+
 // FormatAlertsList formats the list of user's alerts
 func FormatAlertsList(alerts map[string][]AlertInfo) string {
 	if len(alerts) == 0 {
@@ -121,6 +642,7 @@ func FormatAlertsList(alerts map[string][]AlertInfo) string {
 	sb.WriteString("<b>Your Alerts</b>\n\n")
 
 	marketNum := 1
+	trackedMarkets := 0
 	for _, ma := range sortedAlerts {
 		// Create display name with market ID (only if not already included)
 		var displayName string
@@ -137,17 +659,36 @@ func FormatAlertsList(alerts map[string][]AlertInfo) string {
 
 		// Create clickable link to the market (HTML format)
 		marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", ma.marketID)
-		sb.WriteString(fmt.Sprintf("%d. <b><a href=\"%s\">%s</a></b>\n", marketNum, marketURL, displayName))
-
-		// Only show first alert (since we'll limit to 1 per market)
-		if len(ma.alertList) > 0 {
-			sb.WriteString(fmt.Sprintf("Threshold: ±%.1f%%\n", ma.alertList[0].ThresholdPct))
+		sb.WriteString(fmt.Sprintf("%d. <b><a href=\"%s\">%s</a></b>%s\n", marketNum, marketURL, displayName, formatMarketStatus(ma.alertList[0].MarketStatus)))
+
+		// Show one line per alert on the market
+		tracked := false
+		for _, alert := range ma.alertList {
+			line := alert.Condition()
+			if alert.OutcomeName != "" {
+				line = fmt.Sprintf("%s — %s", alert.OutcomeName, line)
+			}
+			if alert.Archived {
+				line = "🗄 " + line + " <i>(archived)</i>"
+			} else if alert.Paused {
+				line = "⏸ " + line + " <i>(paused)</i>"
+			} else {
+				tracked = true
+				if alert.Muted {
+					line = "🔕 " + line + " <i>(snoozed)</i>"
+				}
+			}
+			sb.WriteString(line + "\n")
 		}
 		sb.WriteString("\n")
 		marketNum++
+		if tracked {
+			trackedMarkets++
+		}
 	}
 
-	sb.WriteString(fmt.Sprintf("<i>Total markets tracked: %d/10</i>", len(alerts)))
+	// Markets with only paused alerts don't count toward the limit
+	sb.WriteString(fmt.Sprintf("<i>Total markets tracked: %d/10</i>", trackedMarkets))
 
 	return sb.String()
 }
@@ -182,24 +723,214 @@ func FormatMarketsList(markets []MarketInfo) string {
 
 		// Create clickable link to the market (HTML format)
 		marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", market.MarketID)
-		sb.WriteString(fmt.Sprintf("%d. <a href=\"%s\">%s</a>\n", i+1, marketURL, displayName))
+		sb.WriteString(fmt.Sprintf("%d. <a href=\"%s\">%s</a>%s\n", i+1, marketURL, displayName, formatMarketStatus(market.MarketStatus)))
 	}
 
 	return sb.String()
 }
 
+// formatMarketStatus returns a suffix marking markets that no longer trade (e.g., " (resolved)")
+func formatMarketStatus(status string) string {
+	if status == "" {
+		return ""
+	}
+	return fmt.Sprintf(" <i>(%s)</i>", status)
+}
+
 // AlertInfo holds alert display information
 type AlertInfo struct {
-	ID           int64
-	MarketID     string
-	MarketName   string
-	ThresholdPct float64
+	ID             int64
+	MarketID       string
+	MarketName     string
+	OutcomeName    string
+	AlertType      string
+	Threshold      float64 // Spike threshold in ThresholdUnit
+	ThresholdUnit  string
+	Direction      string
+	Window         time.Duration
+	LevelPrice     float64
+	LevelDirection string
+	VolumeMultiple float64
+	PrintNotional  float64
+	SpreadCents    float64
+	MinLiquidity   float64
+	BandCents      float64
+	MarketStatus   string // storage.MarketStatusClosed or MarketStatusResolved, "" while trading
+	Paused         bool
+	Muted          bool
+	Archived       bool // Market resolved; the alert can't be resumed
+}
+
+// Condition describes when the alert fires (e.g. "Threshold: ±5.0% in 1m")
+func (a AlertInfo) Condition() string {
+	if a.AlertType == storage.AlertTypeCrossing {
+		return fmt.Sprintf("Level: %s $%.4f", a.LevelDirection, a.LevelPrice)
+	}
+	if a.AlertType == storage.AlertTypeVolume {
+		return "Volume: " + FormatVolumeCondition(a.VolumeMultiple, a.PrintNotional, a.Window)
+	}
+	if a.AlertType == storage.AlertTypeSpread || a.AlertType == storage.AlertTypeLiquidity {
+		return "Order book: " + FormatBookCondition(a.AlertType, a.SpreadCents, a.MinLiquidity)
+	}
+	if a.AlertType == storage.AlertTypeConsistency {
+		return "Consistency: " + FormatConsistencyCondition(a.BandCents)
+	}
+	return fmt.Sprintf("Threshold: %s in %s (%s)", FormatThreshold(a.Direction, a.ThresholdUnit, a.Threshold), FormatDuration(a.Window), DirectionLabel(a.Direction))
+}
+
+// HistoryEntry holds one triggered alert shown in the history view
+type HistoryEntry struct {
+	TriggeredAt   time.Time
+	Label         string // Alert name, empty when the page belongs to a single alert
+	PreviousPrice float64
+	CurrentPrice  float64
+	ChangePct     float64
+	SuppressedBy  string // Why the noise filters held the trigger back, empty if it was sent
+}
+
+// FormatAlertHistory formats a page of triggered alerts with a summary of recent trigger counts
+func FormatAlertHistory(title string, entries []HistoryEntry, count24h, count7d, page, totalPages int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📜 <b>%s</b>\n", title))
+	sb.WriteString(fmt.Sprintf("Triggered %d times in the last 24h, %d times in the last 7d\n\n", count24h, count7d))
+
+	if len(entries) == 0 {
+		sb.WriteString(MsgNoHistory)
+		return sb.String()
+	}
+
+	for _, entry := range entries {
+		icon := "🕐"
+		if entry.SuppressedBy != "" {
+			icon = "🔇"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s", icon, entry.TriggeredAt.Format("Jan 02 15:04")))
+		if entry.Label != "" {
+			sb.WriteString(" — " + entry.Label)
+		}
+		sb.WriteString(fmt.Sprintf("\n   $%.4f → $%.4f (%+.2f%%)\n", entry.PreviousPrice, entry.CurrentPrice, entry.ChangePct))
+		if entry.SuppressedBy != "" {
+			sb.WriteString(fmt.Sprintf("   <i>Suppressed: %s</i>\n", entry.SuppressedBy))
+		}
+	}
+
+	if totalPages > 1 {
+		sb.WriteString(fmt.Sprintf("\n<i>Page %d/%d</i>", page+1, totalPages))
+	}
+
+	return sb.String()
+}
+
+// FormatDeferredSummary formats the alerts held back during quiet hours as one message
+func FormatDeferredSummary(entries []HistoryEntry, loc *time.Location) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🌅 <b>While you were away</b>\n%d alerts triggered during your quiet hours:\n\n", len(entries)))
+
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf("🕐 %s", entry.TriggeredAt.In(loc).Format("15:04")))
+		if entry.Label != "" {
+			sb.WriteString(" — " + entry.Label)
+		}
+		sb.WriteString(fmt.Sprintf("\n   $%.4f → $%.4f (%+.2f%%)\n", entry.PreviousPrice, entry.CurrentPrice, entry.ChangePct))
+	}
+
+	return sb.String()
+}
+
+// DigestMarket holds the price summary of one watched outcome in a digest
+type DigestMarket struct {
+	Label string
+	OHLC  storage.PriceOHLC
+}
+
+// FormatDigest formats an hourly or daily digest with each market's price summary and the biggest moves
+func FormatDigest(mode string, markets []DigestMarket, moves []HistoryEntry, triggers int, loc *time.Location) string {
+	title := "Daily Digest"
+	if mode == storage.DigestHourly {
+		title = "Hourly Digest"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📰 <b>%s</b>\n%d alerts triggered\n\n", title, triggers))
+
+	if len(markets) > 0 {
+		sb.WriteString("<b>Markets</b> (open → close, low–high)\n")
+		for _, market := range markets {
+			sb.WriteString(fmt.Sprintf("• %s\n   $%.4f → $%.4f, $%.4f–$%.4f\n",
+				market.Label, market.OHLC.Open, market.OHLC.Close, market.OHLC.Low, market.OHLC.High))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("<b>Biggest moves</b>\n")
+	for _, move := range moves {
+		sb.WriteString(fmt.Sprintf("🕐 %s", move.TriggeredAt.In(loc).Format("Jan 02 15:04")))
+		if move.Label != "" {
+			sb.WriteString(" — " + move.Label)
+		}
+		sb.WriteString(fmt.Sprintf("\n   $%.4f → $%.4f (%+.2f%%)\n", move.PreviousPrice, move.CurrentPrice, move.ChangePct))
+	}
+
+	return sb.String()
+}
+
+// ReminderPrice holds the current price of one watched outcome in a cutoff reminder
+type ReminderPrice struct {
+	Label string
+	Price float64
+}
+
+// FormatCutoffReminder formats the reminder that a tracked market is about to stop trading
+func FormatCutoffReminder(market *storage.Market, remaining time.Duration, prices []ReminderPrice, volume24h string, loc *time.Location) string {
+	marketURL := fmt.Sprintf("https://app.opinion.trade/detail?topicId=%s", market.MarketID)
+	title := market.Title
+	if title == "" {
+		title = "Market #" + market.MarketID
+	}
+
+	var sb strings.Builder
+	sb.WriteString("⏰ <b>Market Closing Soon</b>\n\n")
+	sb.WriteString(fmt.Sprintf("📌 <b>Market:</b> <a href=\"%s\">%s</a>\n\n", marketURL, title))
+	sb.WriteString(fmt.Sprintf("Trading closes in <b>%s</b> (%s)\n", FormatDuration(remaining.Round(time.Minute)), market.CutoffAt.In(loc).Format("Jan 02 15:04")))
+
+	if len(prices) > 0 {
+		sb.WriteString("\n💰 <b>Current prices:</b>\n")
+		for _, price := range prices {
+			sb.WriteString(fmt.Sprintf("   • %s: $%.4f\n", price.Label, price.Price))
+		}
+	}
+
+	if volume24h != "" {
+		sb.WriteString(fmt.Sprintf("\n📊 <b>24h volume:</b> %s\n", volume24h))
+	}
+
+	return sb.String()
+}
+
+// FormatVolume formats a trading volume compactly (e.g. $12.3K)
+func FormatVolume(volume float64) string {
+	switch {
+	case volume >= 1e6:
+		return fmt.Sprintf("$%.1fM", volume/1e6)
+	case volume >= 1e3:
+		return fmt.Sprintf("$%.1fK", volume/1e3)
+	default:
+		return fmt.Sprintf("$%.0f", volume)
+	}
+}
+
+// OutcomeOption holds one selectable outcome of a multi-outcome market
+type OutcomeOption struct {
+	TokenID string
+	Name    string
+	Price   float64 // Current YES price, 0 if unavailable
 }
 
 // MarketInfo holds market display information
 type MarketInfo struct {
-	MarketID   string
-	MarketName string
+	MarketID     string
+	MarketName   string
+	MarketStatus string // storage.MarketStatusClosed or MarketStatusResolved, "" while trading
 }
 
 // FeaturedMarket holds information about a featured market
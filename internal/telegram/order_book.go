@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleSelectSpreadCallback processes the spread limit of a spread alert and asks for the cooldown
+func (b *Bot) handleSelectSpreadCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract cents from callback data (e.g., "select_spread_5")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid spread callback data: %s", callback.Data)
+		return
+	}
+
+	cents, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || cents <= 0 {
+		b.log.Errorf("Failed to parse spread: %s", parts[2])
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state.MarketID == "" {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	// Delete the spread selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
+
+	state.Data["spread_cents"] = cents
+	b.promptCooldown(callback.Message.Chat.ID, state, fmt.Sprintf("<b>Spread:</b> at least %g¢", cents))
+}
+
+// handleSpreadInput processes a custom spread limit in cents
+func (b *Bot) handleSpreadInput(ctx context.Context, message *tgbotapi.Message) {
+	cents, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(message.Text), "¢"), 64)
+	if err != nil || cents < 0.1 || cents > 99 {
+		b.SendMessage(message.Chat.ID, MsgInvalidSpread, nil)
+		return
+	}
+
+	state := b.getUserState(message.From.ID)
+	state.Data["spread_cents"] = cents
+	b.promptCooldown(message.Chat.ID, state, fmt.Sprintf("<b>Spread:</b> at least %g¢", cents))
+}
+
+// handleSelectLiquidityCallback processes the liquidity limit of a liquidity alert and asks for the cooldown
+func (b *Bot) handleSelectLiquidityCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract dollars from callback data (e.g., "select_liquidity_500")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid liquidity callback data: %s", callback.Data)
+		return
+	}
+
+	value, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || value <= 0 {
+		b.log.Errorf("Failed to parse liquidity: %s", parts[2])
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state.MarketID == "" {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	// Delete the liquidity selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
+
+	state.Data["min_liquidity"] = value
+	b.promptCooldown(callback.Message.Chat.ID, state, "<b>Top of book:</b> below "+FormatVolume(value))
+}
+
+// handleLiquidityInput processes a custom liquidity limit in dollars
+func (b *Bot) handleLiquidityInput(ctx context.Context, message *tgbotapi.Message) {
+	value, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(message.Text), "$"), 64)
+	if err != nil || value < 1 || value > 1000000 {
+		b.SendMessage(message.Chat.ID, MsgInvalidLiquidity, nil)
+		return
+	}
+
+	state := b.getUserState(message.From.ID)
+	state.Data["min_liquidity"] = value
+	b.promptCooldown(message.Chat.ID, state, "<b>Top of book:</b> below "+FormatVolume(value))
+}
+
+// promptCooldown asks for the cooldown of an order book or consistency alert, which has no lookback window
+func (b *Bot) promptCooldown(chatID int64, state *UserState, header string) {
+	state.Step = "awaiting_cooldown"
+	b.SendMessage(chatID, fmt.Sprintf("%s\n\n%s", header, MsgCooldownPrompt), BuildCooldownSelectionMenu(b.defaultCooldown))
+}
@@ -0,0 +1,349 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Telegram rate limits: about 30 messages per second overall and 1 per second in a single chat
+const (
+	globalSendRate  = 30
+	globalSendBurst = 30
+	chatSendRate    = 1
+	chatSendBurst   = 3
+
+	// senderStatsInterval is how often queue metrics are logged
+	senderStatsInterval = time.Minute
+	// queueDepthWarning is the queue depth above which the sender warns it is falling behind
+	queueDepthWarning = 100
+)
+
+// Priority orders queued messages; lower values are sent first
+type Priority int
+
+const (
+	PriorityAlert Priority = iota // Alert notifications
+	PriorityMenu                  // Replies, menus and message edits
+
+	priorityCount
+)
+
+// ErrSenderStopped is returned for messages that can no longer be sent because the bot is shutting down
+var ErrSenderStopped = errors.New("telegram sender stopped")
+
+// SenderStats is a snapshot of the send queue metrics
+type SenderStats struct {
+	AlertQueue int    // Alert notifications waiting to be sent
+	MenuQueue  int    // Other messages waiting to be sent
+	Sent       uint64 // Messages sent since start
+	Failed     uint64 // Messages Telegram rejected since start
+	RateLimits uint64 // 429 responses since start
+}
+
+// sendRequest is a message waiting in the send queue
+type sendRequest struct {
+	chatID int64
+	msg    tgbotapi.Chattable
+	result chan sendResult
+}
+
+// sendResult is the outcome of a queued send
+type sendResult struct {
+	message tgbotapi.Message
+	err     error
+}
+
+// tokenBucket allows rate sends per second with bursts of up to burst sends
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// refill adds the tokens accumulated since the last refill
+func (t *tokenBucket) refill(now time.Time) {
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+}
+
+// wait returns how long until a token is available, 0 if one is available now
+func (t *tokenBucket) wait(now time.Time) time.Duration {
+	t.refill(now)
+	if t.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+}
+
+// take consumes a token; callers check wait first
+func (t *tokenBucket) take() {
+	t.tokens--
+}
+
+// full reports whether the bucket has refilled completely
+func (t *tokenBucket) full(now time.Time) bool {
+	t.refill(now)
+	return t.tokens >= t.burst
+}
+
+// Sender sends every outgoing message through one queue, keeping within Telegram's global
+// and per-chat rate limits and sending alert notifications ahead of menu updates
+type Sender struct {
+	api *tgbotapi.BotAPI
+	log *logrus.Logger
+
+	mu          sync.Mutex
+	queues      [priorityCount][]*sendRequest
+	global      *tokenBucket
+	chats       map[int64]*tokenBucket
+	pausedUntil time.Time // Set when Telegram asks to back off after a 429
+	stopped     bool
+	stats       SenderStats
+
+	wake chan struct{}
+}
+
+// NewSender creates a new send queue for the given bot API
+func NewSender(api *tgbotapi.BotAPI, log *logrus.Logger) *Sender {
+	return &Sender{
+		api:    api,
+		log:    log,
+		global: newTokenBucket(globalSendRate, globalSendBurst, time.Now()),
+		chats:  make(map[int64]*tokenBucket),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Send queues a message and waits until it has been sent. If the context is cancelled while the
+// message is still queued, it is dropped from the queue and the context's error is returned
+func (s *Sender) Send(ctx context.Context, msg tgbotapi.Chattable, priority Priority) (tgbotapi.Message, error) {
+	req := &sendRequest{
+		chatID: chatIDOf(msg),
+		msg:    msg,
+		result: make(chan sendResult, 1),
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return tgbotapi.Message{}, ErrSenderStopped
+	}
+	s.queues[priority] = append(s.queues[priority], req)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case res := <-req.result:
+		return res.message, res.err
+	case <-ctx.Done():
+	}
+
+	if s.dequeue(req, priority) {
+		return tgbotapi.Message{}, ctx.Err()
+	}
+
+	// The message is already being sent, so wait for the outcome rather than report it unsent
+	res := <-req.result
+	return res.message, res.err
+}
+
+// dequeue removes a message that is still waiting in the queue, reporting whether it was there
+func (s *Sender) dequeue(req *sendRequest, priority Priority) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.queues[priority]
+	for i, queued := range queue {
+		if queued == req {
+			s.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the current queue metrics
+func (s *Sender) Stats() SenderStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.AlertQueue = len(s.queues[PriorityAlert])
+	stats.MenuQueue = len(s.queues[PriorityMenu])
+	return stats
+}
+
+// Start sends queued messages until the context is cancelled
+func (s *Sender) Start(ctx context.Context) {
+	statsTicker := time.NewTicker(senderStatsInterval)
+	defer statsTicker.Stop()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		req, wait := s.next(time.Now())
+		if req != nil {
+			s.send(req)
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if wait > 0 {
+			timer.Reset(wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.stop()
+			return
+		case <-s.wake:
+		case <-timer.C:
+		case <-statsTicker.C:
+			s.logStats()
+		}
+	}
+}
+
+// next removes and returns the first queued message that may be sent now, highest priority first.
+// If none can be sent, it returns how long until one might be, or 0 if the queue is empty
+func (s *Sender) next(now time.Time) (*sendRequest, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queues[PriorityAlert])+len(s.queues[PriorityMenu]) == 0 {
+		return nil, 0
+	}
+	if now.Before(s.pausedUntil) {
+		return nil, s.pausedUntil.Sub(now)
+	}
+	if wait := s.global.wait(now); wait > 0 {
+		return nil, wait
+	}
+
+	var minWait time.Duration
+	for priority := range s.queues {
+		queue := s.queues[priority]
+		for i, req := range queue {
+			bucket := s.chatBucket(req.chatID, now)
+			if wait := bucket.wait(now); wait > 0 {
+				if minWait == 0 || wait < minWait {
+					minWait = wait
+				}
+				continue
+			}
+
+			bucket.take()
+			s.global.take()
+			s.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return req, 0
+		}
+	}
+
+	return nil, minWait
+}
+
+// chatBucket returns the token bucket of a chat, creating it on first use
+func (s *Sender) chatBucket(chatID int64, now time.Time) *tokenBucket {
+	bucket, ok := s.chats[chatID]
+	if !ok {
+		bucket = newTokenBucket(chatSendRate, chatSendBurst, now)
+		s.chats[chatID] = bucket
+	}
+	return bucket
+}
+
+// send delivers one message and reports the outcome to the waiting caller
+func (s *Sender) send(req *sendRequest) {
+	message, err := s.api.Send(req.msg)
+
+	s.mu.Lock()
+	switch retryAfter, limited := RetryAfter(err); {
+	case limited:
+		s.stats.RateLimits++
+		s.pausedUntil = time.Now().Add(retryAfter)
+		s.log.Warnf("Telegram rate limit hit in chat %d, pausing sends for %v", req.chatID, retryAfter)
+	case err != nil:
+		s.stats.Failed++
+	default:
+		s.stats.Sent++
+	}
+	s.mu.Unlock()
+
+	req.result <- sendResult{message: message, err: err}
+}
+
+// stop fails every queued message and rejects new ones
+func (s *Sender) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	for priority, queue := range s.queues {
+		for _, req := range queue {
+			req.result <- sendResult{err: ErrSenderStopped}
+		}
+		s.queues[priority] = nil
+	}
+}
+
+// logStats logs the queue metrics and forgets chats that have been idle long enough to refill
+func (s *Sender) logStats() {
+	stats := s.Stats()
+	depth := stats.AlertQueue + stats.MenuQueue
+
+	logf := s.log.Debugf
+	if depth > queueDepthWarning {
+		logf = s.log.Warnf
+	}
+	logf("Send queue: %d alerts, %d other queued; %d sent, %d failed, %d rate limited",
+		stats.AlertQueue, stats.MenuQueue, stats.Sent, stats.Failed, stats.RateLimits)
+
+	now := time.Now()
+	s.mu.Lock()
+	for chatID, bucket := range s.chats {
+		if bucket.full(now) {
+			delete(s.chats, chatID)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// chatIDOf returns the chat a message is sent to, or 0 if it isn't tied to one
+func chatIDOf(msg tgbotapi.Chattable) int64 {
+	switch m := msg.(type) {
+	case tgbotapi.MessageConfig:
+		return m.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return m.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return m.ChatID
+	case tgbotapi.DeleteMessageConfig:
+		return m.ChatID
+	default:
+		return 0
+	}
+}
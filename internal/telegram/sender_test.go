@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		rate      float64
+		burst     float64
+		takes     int           // Tokens taken at start
+		after     time.Duration // Time since start the bucket is checked at
+		wantWait  time.Duration
+		wantFull  bool
+		tolerance time.Duration
+	}{
+		{name: "full bucket", rate: 1, burst: 3, takes: 0, after: 0, wantWait: 0, wantFull: true},
+		{name: "burst partly used", rate: 1, burst: 3, takes: 2, after: 0, wantWait: 0, wantFull: false},
+		{name: "burst used up", rate: 1, burst: 3, takes: 3, after: 0, wantWait: time.Second},
+		{name: "partly refilled", rate: 1, burst: 3, takes: 3, after: 400 * time.Millisecond, wantWait: 600 * time.Millisecond},
+		{name: "refilled one token", rate: 1, burst: 3, takes: 3, after: time.Second, wantWait: 0},
+		{name: "refill stops at the burst", rate: 1, burst: 3, takes: 3, after: time.Hour, wantWait: 0, wantFull: true},
+		{name: "faster rate", rate: 30, burst: 30, takes: 30, after: 0, wantWait: time.Second / 30, tolerance: time.Microsecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := newTokenBucket(tt.rate, tt.burst, start)
+			for i := 0; i < tt.takes; i++ {
+				bucket.take()
+			}
+
+			now := start.Add(tt.after)
+			if got := bucket.wait(now); got < tt.wantWait-tt.tolerance || got > tt.wantWait+tt.tolerance {
+				t.Errorf("wait() = %v, want %v", got, tt.wantWait)
+			}
+			if got := bucket.full(now); got != tt.wantFull {
+				t.Errorf("full() = %v, want %v", got, tt.wantFull)
+			}
+		})
+	}
+}
+
+// newTestSender creates a sender whose rate limits start full at now
+func newTestSender(now time.Time) *Sender {
+	s := NewSender(nil, logrus.New())
+	s.global = newTokenBucket(globalSendRate, globalSendBurst, now)
+	return s
+}
+
+// queue adds a message to the sender's queue without waiting for it to be sent
+func queue(s *Sender, chatID int64, priority Priority) *sendRequest {
+	req := &sendRequest{chatID: chatID, msg: tgbotapi.NewMessage(chatID, ""), result: make(chan sendResult, 1)}
+	s.queues[priority] = append(s.queues[priority], req)
+	return req
+}
+
+func TestSenderNext(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("empty queue", func(t *testing.T) {
+		s := newTestSender(now)
+		if req, wait := s.next(now); req != nil || wait != 0 {
+			t.Errorf("next() = %v, %v, want nil, 0", req, wait)
+		}
+	})
+
+	t.Run("alerts go before menus", func(t *testing.T) {
+		s := newTestSender(now)
+		menu := queue(s, 1, PriorityMenu)
+		alert := queue(s, 2, PriorityAlert)
+
+		if req, _ := s.next(now); req != alert {
+			t.Error("first message sent isn't the alert")
+		}
+		if req, _ := s.next(now); req != menu {
+			t.Error("second message sent isn't the menu")
+		}
+	})
+
+	t.Run("chat over its limit doesn't hold up other chats", func(t *testing.T) {
+		s := newTestSender(now)
+		for i := 0; i < chatSendBurst+1; i++ {
+			queue(s, 1, PriorityAlert)
+		}
+		other := queue(s, 2, PriorityAlert)
+
+		for i := 0; i < chatSendBurst; i++ {
+			if req, _ := s.next(now); req == nil || req.chatID != 1 {
+				t.Fatalf("message %d not sent to chat 1", i+1)
+			}
+		}
+		if req, _ := s.next(now); req != other {
+			t.Fatal("message to chat 2 held up by chat 1")
+		}
+
+		req, wait := s.next(now)
+		if req != nil {
+			t.Fatal("chat 1 sent over its limit")
+		}
+		if want := time.Second / chatSendRate; wait != want {
+			t.Errorf("wait = %v, want %v", wait, want)
+		}
+		if req, _ := s.next(now.Add(wait)); req == nil || req.chatID != 1 {
+			t.Error("chat 1 not sent to once its limit refilled")
+		}
+	})
+
+	t.Run("global limit", func(t *testing.T) {
+		s := newTestSender(now)
+		for chatID := int64(1); chatID <= globalSendBurst+1; chatID++ {
+			queue(s, chatID, PriorityAlert)
+		}
+
+		for i := 0; i < globalSendBurst; i++ {
+			if req, _ := s.next(now); req == nil {
+				t.Fatalf("message %d not sent within the global burst", i+1)
+			}
+		}
+		if req, wait := s.next(now); req != nil || wait <= 0 {
+			t.Errorf("next() = %v, %v, want nil and a wait", req, wait)
+		}
+	})
+
+	t.Run("paused after a rate limit", func(t *testing.T) {
+		s := newTestSender(now)
+		s.pausedUntil = now.Add(5 * time.Second)
+		queue(s, 1, PriorityAlert)
+
+		if req, wait := s.next(now); req != nil || wait != 5*time.Second {
+			t.Errorf("next() = %v, %v, want nil, 5s", req, wait)
+		}
+		if req, _ := s.next(s.pausedUntil); req == nil {
+			t.Error("message not sent once the pause ended")
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// showSettings displays the user's settings card with an optional status line on top
+// The card replaces messageID, or is sent as a new message when messageID is 0
+func (b *Bot) showSettings(ctx context.Context, chatID int64, messageID int, telegramID int64, status string) {
+	user, err := b.storage.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		b.log.Errorf("Failed to get user: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	text := FormatUserSettings(user)
+	if status != "" {
+		text = status + "\n\n" + text
+	}
+	keyboard := BuildSettingsMenu()
+
+	if messageID == 0 {
+		b.SendMessage(chatID, text, keyboard)
+		return
+	}
+
+	msg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleSetTimezoneCallback asks for the user's timezone
+func (b *Bot) handleSetTimezoneCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	state := b.getUserState(callback.From.ID)
+	state.Step = "awaiting_timezone"
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgTimezonePrompt)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Back to Settings", CallbackSettings),
+		),
+	)
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleTimezoneInput processes the user's timezone
+func (b *Bot) handleTimezoneInput(ctx context.Context, message *tgbotapi.Message) {
+	timezone := strings.TrimSpace(message.Text)
+
+	// "Local" would silently follow the server timezone, which is what an empty timezone already means
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		b.SendMessage(message.Chat.ID, MsgInvalidTimezone, nil)
+		return
+	}
+
+	b.updateSettings(ctx, message.Chat.ID, message.From.ID, func(user *storage.User) {
+		user.Timezone = timezone
+	})
+}
+
+// handleQuietHoursCallback processes quiet hours selection (e.g., "quiet_hours_22-7", "quiet_hours_off")
+func (b *Bot) handleQuietHoursCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid quiet hours callback data: %s", callback.Data)
+		return
+	}
+
+	switch parts[2] {
+	case SettingMenu:
+		msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgQuietHoursPrompt)
+		keyboard := BuildQuietHoursMenu()
+		msg.ReplyMarkup = &keyboard
+		b.send(msg)
+	case SettingCustom:
+		state := b.getUserState(callback.From.ID)
+		state.Step = "awaiting_quiet_hours"
+
+		msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgQuietHoursCustom)
+		b.send(msg)
+	case SettingOff:
+		b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+		b.updateSettings(ctx, callback.Message.Chat.ID, callback.From.ID, func(user *storage.User) {
+			user.QuietStart = nil
+			user.QuietEnd = nil
+		})
+	default:
+		start, end, err := parseQuietHours(parts[2])
+		if err != nil {
+			b.log.Errorf("Invalid quiet hours in callback: %s", parts[2])
+			return
+		}
+
+		b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+		b.updateSettings(ctx, callback.Message.Chat.ID, callback.From.ID, func(user *storage.User) {
+			user.QuietStart = &start
+			user.QuietEnd = &end
+		})
+	}
+}
+
+// handleQuietHoursInput processes custom quiet hours (e.g., "22-7")
+func (b *Bot) handleQuietHoursInput(ctx context.Context, message *tgbotapi.Message) {
+	start, end, err := parseQuietHours(strings.TrimSpace(message.Text))
+	if err != nil {
+		b.SendMessage(message.Chat.ID, MsgInvalidQuietHours, nil)
+		return
+	}
+
+	b.updateSettings(ctx, message.Chat.ID, message.From.ID, func(user *storage.User) {
+		user.QuietStart = &start
+		user.QuietEnd = &end
+	})
+}
+
+// handleUrgentThresholdCallback processes urgent threshold selection (e.g., "urgent_threshold_25", "urgent_threshold_off")
+func (b *Bot) handleUrgentThresholdCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid urgent threshold callback data: %s", callback.Data)
+		return
+	}
+
+	switch parts[2] {
+	case SettingMenu:
+		msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgUrgentPrompt)
+		keyboard := BuildUrgentThresholdMenu()
+		msg.ReplyMarkup = &keyboard
+		b.send(msg)
+	case SettingOff:
+		b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+		b.updateSettings(ctx, callback.Message.Chat.ID, callback.From.ID, func(user *storage.User) {
+			user.UrgentThresholdPct = nil
+		})
+	default:
+		threshold, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil || threshold <= 0 {
+			b.log.Errorf("Invalid urgent threshold in callback: %s", parts[2])
+			return
+		}
+
+		b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+		b.updateSettings(ctx, callback.Message.Chat.ID, callback.From.ID, func(user *storage.User) {
+			user.UrgentThresholdPct = &threshold
+		})
+	}
+}
+
+// handleDigestModeCallback processes digest mode selection (e.g., "digest_mode_daily")
+func (b *Bot) handleDigestModeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid digest mode callback data: %s", callback.Data)
+		return
+	}
+
+	mode := parts[2]
+	switch mode {
+	case SettingMenu:
+		msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgDigestPrompt)
+		keyboard := BuildDigestModeMenu()
+		msg.ReplyMarkup = &keyboard
+		b.send(msg)
+		return
+	case storage.DigestOff, storage.DigestHourly, storage.DigestDaily:
+	default:
+		b.log.Errorf("Unknown digest mode in callback: %s", mode)
+		return
+	}
+
+	b.send(tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID))
+	b.updateSettings(ctx, callback.Message.Chat.ID, callback.From.ID, func(user *storage.User) {
+		user.DigestMode = mode
+	})
+}
+
+// handleCutoffRemindersCallback shows the cutoff reminder toggles or turns one offset on or off
+// (e.g., "cutoff_reminders_menu", "cutoff_reminders_3600")
+func (b *Bot) handleCutoffRemindersCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid cutoff reminders callback data: %s", callback.Data)
+		return
+	}
+
+	user, err := b.storage.GetUserByTelegramID(ctx, callback.From.ID)
+	if err != nil {
+		b.log.Errorf("Failed to get user: %v", err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	if parts[2] != SettingMenu {
+		seconds, err := strconv.Atoi(parts[2])
+		if err != nil || seconds <= 0 {
+			b.log.Errorf("Invalid reminder offset in callback: %s", parts[2])
+			return
+		}
+
+		// Toggle the offset
+		toggled := time.Duration(seconds) * time.Second
+		var offsets []time.Duration
+		found := false
+		for _, offset := range user.ReminderOffsets() {
+			if offset == toggled {
+				found = true
+				continue
+			}
+			offsets = append(offsets, offset)
+		}
+		if !found {
+			offsets = append(offsets, toggled)
+		}
+		user.SetReminderOffsets(offsets)
+
+		if err := b.storage.UpdateUserSettings(ctx, user); err != nil {
+			b.log.Errorf("Failed to update settings of user %d: %v", callback.From.ID, err)
+			b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+			return
+		}
+	}
+
+	msg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, MsgRemindersPrompt)
+	keyboard := BuildCutoffRemindersMenu(user.ReminderOffsets())
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// updateSettings applies a change to the user's settings and shows the settings card again
+func (b *Bot) updateSettings(ctx context.Context, chatID, telegramID int64, change func(user *storage.User)) {
+	b.clearUserState(telegramID)
+
+	user, err := b.storage.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		b.log.Errorf("Failed to get user: %v", err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	change(user)
+
+	if err := b.storage.UpdateUserSettings(ctx, user); err != nil {
+		b.log.Errorf("Failed to update settings of user %d: %v", telegramID, err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	b.SendMessage(chatID, MsgSettingsUpdated+"\n\n"+FormatUserSettings(user), BuildSettingsMenu())
+}
+
+// parseQuietHours parses a quiet hours window given as start and end hour (e.g., "22-7")
+func parseQuietHours(s string) (int, int, error) {
+	bounds := strings.Split(s, "-")
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("expected start-end, got %q", s)
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil || start < 0 || start > 23 {
+		return 0, 0, fmt.Errorf("invalid start hour %q", bounds[0])
+	}
+
+	end, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+	if err != nil || end < 0 || end > 23 {
+		return 0, 0, fmt.Errorf("invalid end hour %q", bounds[1])
+	}
+
+	if start == end {
+		return 0, 0, fmt.Errorf("quiet hours must not be empty")
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/qmitry/opinion-alert-bot/internal/storage"
+)
+
+// thresholdRaiseFactor is how much the "raise threshold" notification action raises a spike threshold by
+const thresholdRaiseFactor = 1.5
+
+// handleSnoozeCallback snoozes an alert from its notification (format: "snooze_<alertID>_<seconds>")
+func (b *Bot) handleSnoozeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid snooze callback data: %s", callback.Data)
+		return
+	}
+
+	seconds, err := strconv.Atoi(parts[2])
+	if err != nil || seconds <= 0 {
+		b.log.Errorf("Invalid snooze duration in callback: %s", parts[2])
+		return
+	}
+
+	alert := b.loadCallbackAlert(ctx, callback, parts[1])
+	if alert == nil {
+		return
+	}
+
+	b.muteAlert(ctx, callback.Message.Chat.ID, alert, time.Now().Add(time.Duration(seconds)*time.Second))
+}
+
+// alertLocation returns the timezone of the alert's owner, falling back to the server timezone
+func (b *Bot) alertLocation(ctx context.Context, alert *storage.Alert) *time.Location {
+	user, err := b.storage.GetUserByID(ctx, alert.UserID)
+	if err != nil {
+		b.log.Warnf("Failed to get user %d: %v", alert.UserID, err)
+		return time.Local
+	}
+	return user.Location()
+}
+
+// handleMuteTomorrowCallback mutes an alert until the start of the user's next day (format: "mute_tomorrow_<alertID>")
+func (b *Bot) handleMuteTomorrowCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alert := b.loadCallbackAlert(ctx, callback, callbackSuffix(callback.Data))
+	if alert == nil {
+		return
+	}
+
+	now := time.Now().In(b.alertLocation(ctx, alert))
+	year, month, day := now.Date()
+	b.muteAlert(ctx, callback.Message.Chat.ID, alert, time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()))
+}
+
+// handleUnmuteAlertCallback lifts the snooze of an alert from its settings card (format: "unmute_alert_<alertID>")
+func (b *Bot) handleUnmuteAlertCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alert := b.loadCallbackAlert(ctx, callback, callbackSuffix(callback.Data))
+	if alert == nil {
+		return
+	}
+
+	if err := b.storage.SetAlertMutedUntil(ctx, alert.ID, alert.UserID, nil); err != nil {
+		b.log.Errorf("Failed to unmute alert %d: %v", alert.ID, err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+	alert.MutedUntil = nil
+
+	msg := tgbotapi.NewEditMessageText(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		MsgAlertUnmuted+"\n\n"+FormatAlertSettings(alert, b.defaultCooldown),
+	)
+	msg.ParseMode = "HTML"
+	keyboard := BuildEditAlertMenu(alert)
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}
+
+// handleRaiseThresholdCallback raises the threshold of a spike alert from its notification (format: "raise_threshold_<alertID>")
+func (b *Bot) handleRaiseThresholdCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alert := b.loadCallbackAlert(ctx, callback, callbackSuffix(callback.Data))
+	if alert == nil {
+		return
+	}
+
+	raised, ok := raisedThreshold(alert)
+	if !ok {
+		b.SendMessage(callback.Message.Chat.ID, MsgThresholdAtMax, nil)
+		return
+	}
+
+	setThreshold(alert.ThresholdUnit, raised)(alert)
+	if err := b.storage.UpdateAlertSettings(ctx, alert); err != nil {
+		b.log.Errorf("Failed to raise threshold of alert %d: %v", alert.ID, err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	b.SendMessage(callback.Message.Chat.ID, fmt.Sprintf(MsgThresholdRaised, FormatThreshold(alert.Direction, alert.ThresholdUnit, raised)), nil)
+}
+
+// handleAlertHistoryCallback opens the history of an alert from its notification (format: "alert_history_<alertID>")
+func (b *Bot) handleAlertHistoryCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	alertID, err := strconv.ParseInt(callbackSuffix(callback.Data), 10, 64)
+	if err != nil {
+		b.log.Errorf("Invalid alert ID in callback: %s", callback.Data)
+		return
+	}
+
+	// Sent as a new message so the notification stays in the chat
+	b.showAlertHistory(ctx, callback.Message.Chat.ID, 0, callback.From.ID, alertID, 0)
+}
+
+// muteAlert stores the snooze of an alert and confirms it to the user
+func (b *Bot) muteAlert(ctx context.Context, chatID int64, alert *storage.Alert, until time.Time) {
+	if err := b.storage.SetAlertMutedUntil(ctx, alert.ID, alert.UserID, &until); err != nil {
+		b.log.Errorf("Failed to mute alert %d: %v", alert.ID, err)
+		b.SendMessage(chatID, MsgErrorOccurred, BuildMainMenu())
+		return
+	}
+
+	b.SendMessage(chatID, fmt.Sprintf(MsgAlertSnoozed, until.In(b.alertLocation(ctx, alert)).Format("Jan 02 15:04")), nil)
+}
+
+// loadCallbackAlert loads the alert with the given ID from callback data, making sure it belongs to the user
+// Returns nil after notifying the user if the alert can't be loaded
+func (b *Bot) loadCallbackAlert(ctx context.Context, callback *tgbotapi.CallbackQuery, idStr string) *storage.Alert {
+	alertID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		b.log.Errorf("Invalid alert ID in callback: %s", callback.Data)
+		return nil
+	}
+
+	alert, err := b.getUserAlert(ctx, callback.From.ID, alertID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(callback.Message.Chat.ID, MsgAlertNotFound, BuildMainMenu())
+			return nil
+		}
+		b.log.Errorf("Failed to get alert %d: %v", alertID, err)
+		b.SendMessage(callback.Message.Chat.ID, MsgErrorOccurred, BuildMainMenu())
+		return nil
+	}
+
+	return alert
+}
+
+// callbackSuffix returns the part of callback data after its last underscore (e.g., "mute_tomorrow_123" -> "123")
+func callbackSuffix(data string) string {
+	return data[strings.LastIndex(data, "_")+1:]
+}
+
+// raisedThreshold returns the threshold the "raise threshold" action would set, rounded up to half a unit
+// Returns false for alerts other than spikes and thresholds already at the maximum
+func raisedThreshold(alert *storage.Alert) (float64, bool) {
+	if alert.AlertType != storage.AlertTypeSpike {
+		return 0, false
+	}
+
+	maxThreshold := 100.0
+	switch alert.ThresholdUnit {
+	case storage.ThresholdUnitCents:
+		maxThreshold = 99
+	case storage.ThresholdUnitSigma:
+		maxThreshold = 10
+	}
+
+	current := alert.ThresholdValue()
+	if current >= maxThreshold {
+		return 0, false
+	}
+
+	raised := math.Ceil(current*thresholdRaiseFactor*2) / 2
+	return math.Min(raised, maxThreshold), true
+}
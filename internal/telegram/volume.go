@@ -0,0 +1,113 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleSelectMultipleCallback processes the volume surge multiple of a volume alert and asks for the trade value
+func (b *Bot) handleSelectMultipleCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract multiple from callback data (e.g., "select_multiple_3" or "select_multiple_off")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid multiple callback data: %s", callback.Data)
+		return
+	}
+
+	multiple := 0.0
+	if parts[2] != SettingOff {
+		var err error
+		multiple, err = strconv.ParseFloat(parts[2], 64)
+		if err != nil || multiple <= 0 {
+			b.log.Errorf("Failed to parse multiple: %s", parts[2])
+			return
+		}
+	}
+
+	state := b.getUserState(callback.From.ID)
+	if state.MarketID == "" {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	// Delete the multiple selection message
+	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	b.send(deleteMsg)
+
+	b.promptTradeValue(callback.Message.Chat.ID, state, multiple)
+}
+
+// handleMultipleInput processes a custom volume surge multiple
+func (b *Bot) handleMultipleInput(ctx context.Context, message *tgbotapi.Message) {
+	multiple, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(message.Text), "x"), 64)
+	if err != nil || multiple < 1.5 || multiple > 100 {
+		b.SendMessage(message.Chat.ID, MsgInvalidMultiple, nil)
+		return
+	}
+
+	b.promptTradeValue(message.Chat.ID, b.getUserState(message.From.ID), multiple)
+}
+
+// promptTradeValue stores the chosen surge multiple (0 if off) and asks for the large trade value
+func (b *Bot) promptTradeValue(chatID int64, state *UserState, multiple float64) {
+	state.Data["volume_multiple"] = multiple
+	delete(state.Data, "print_notional")
+	state.Step = "awaiting_trade_value"
+
+	header := "<b>Volume surge:</b> off"
+	if multiple > 0 {
+		header = fmt.Sprintf("<b>Volume surge:</b> %g× the average", multiple)
+	}
+	b.SendMessage(chatID, fmt.Sprintf("%s\n\n%s", header, MsgTradeValuePrompt), BuildTradeValueMenu(multiple > 0))
+}
+
+// handleSelectTradeCallback processes the large trade value of a volume alert and asks for the window
+func (b *Bot) handleSelectTradeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	// Extract trade value from callback data (e.g., "select_trade_5000" or "select_trade_off")
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) != 3 {
+		b.log.Errorf("Invalid trade value callback data: %s", callback.Data)
+		return
+	}
+
+	state := b.getUserState(callback.From.ID)
+	multiple, ok := state.Data["volume_multiple"].(float64)
+	if state.MarketID == "" || !ok {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Please start by creating an alert first.")
+		b.send(msg)
+		return
+	}
+
+	notional := 0.0
+	if parts[2] != SettingOff {
+		value, err := strconv.Atoi(parts[2])
+		if err != nil || value <= 0 {
+			b.log.Errorf("Failed to parse trade value: %s", parts[2])
+			return
+		}
+		notional = float64(value)
+	}
+
+	// A volume alert needs at least one of its two conditions
+	if multiple == 0 && notional == 0 {
+		b.log.Errorf("Volume alert without a condition in callback: %s", callback.Data)
+		return
+	}
+	state.Data["print_notional"] = notional
+	state.Step = "awaiting_window"
+
+	// Ask for the lookback window
+	msg := tgbotapi.NewEditMessageText(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		MsgVolumeWindowPrompt,
+	)
+	keyboard := BuildWindowSelectionMenu()
+	msg.ReplyMarkup = &keyboard
+	b.send(msg)
+}